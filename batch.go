@@ -0,0 +1,65 @@
+package xmlrpc
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultBatchConcurrency is the default number of calls of a
+// [Client.CallBatch] batch that run at the same time.
+const defaultBatchConcurrency = 4
+
+// BatchCall is a single call of a [Client.CallBatch] batch.
+type BatchCall struct {
+	// Method is the name of the method to call.
+	Method string
+	// Args are the arguments of the call, as passed to [Client.CallContext].
+	Args any
+	// Reply is an optional pointer the result of the call is decoded into.
+	Reply any
+}
+
+// BatchResult is the outcome of a single call of a [Client.CallBatch] batch.
+// At most one of Fault and Err is set; see [Client.CallWithResult].
+type BatchResult struct {
+	// Value is the Reply of the call the result was decoded into or, if
+	// Reply was nil, the result decoded as described for [Unmarshal].
+	Value any
+	// Fault is the fault returned by the server, if any.
+	Fault *FaultError
+	// Err is the error of a call that failed before a valid response was
+	// decoded.
+	Err error
+}
+
+// CallBatch invokes several methods as separate, concurrent requests over the
+// client's HTTP client and returns their results in the order of calls. Unlike
+// [Client.MultiCall], it does not require the server to support
+// system.multicall. At most the number of calls set with
+// [WithBatchConcurrency] run at the same time.
+//
+// A failed call does not abort the others; its fault or error is reported in
+// its result. Canceling ctx fails the calls that have not completed yet.
+func (c *Client) CallBatch(ctx context.Context, calls []BatchCall) []BatchResult {
+	results := make([]BatchResult, len(calls))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(c.batchConcurrency, len(calls)) {
+		wg.Go(func() {
+			for i := range indexes {
+				call := calls[i]
+				value, fault, err := c.CallWithResult(ctx, call.Method, call.Args, call.Reply)
+				results[i] = BatchResult{Value: value, Fault: fault, Err: err}
+			}
+		})
+	}
+
+	for i := range calls {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
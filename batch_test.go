@@ -0,0 +1,111 @@
+package xmlrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallBatch(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning atomic.Int32
+	argRe := regexp.MustCompile(`<int>(\d+)</int>`)
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		match := argRe.FindSubmatch(body)
+		if match == nil {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		arg, _ := strconv.Atoi(string(match[1]))
+		// Answer later calls first, so results arrive out of order.
+		time.Sleep(time.Duration(10-arg) * time.Millisecond)
+
+		var resp string
+		if arg == 3 {
+			resp = `<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value><int>3</int></value></member>` +
+				`<member><name>faultString</name><value><string>bad argument</string></value></member>` +
+				`</struct></value></fault></methodResponse>`
+		} else {
+			resp = fmt.Sprintf(
+				`<methodResponse><params><param><value><int>%d</int></value></param></params></methodResponse>`,
+				arg*10,
+			)
+		}
+		if _, err := io.WriteString(w, resp); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL, WithBatchConcurrency(2))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var first int
+	calls := []BatchCall{
+		{Method: "math.times", Args: 1, Reply: &first},
+		{Method: "math.times", Args: 2},
+		{Method: "math.times", Args: 3},
+		{Method: "math.times"},
+		{Method: "math.times", Args: 5},
+	}
+	results := client.CallBatch(context.Background(), calls)
+	if len(results) != len(calls) {
+		t.Fatalf("expected %d results, got %d", len(calls), len(results))
+	}
+
+	if results[0].Value != &first || first != 10 || results[0].Fault != nil || results[0].Err != nil {
+		t.Errorf("result 0: expected reply holding 10, got %+v (first=%d)", results[0], first)
+	}
+	for _, i := range []int{1, 4} {
+		if want := int64((i + 1) * 10); results[i].Value != want || results[i].Fault != nil || results[i].Err != nil {
+			t.Errorf("result %d: expected %d, got %+v", i, want, results[i])
+		}
+	}
+	if f := results[2].Fault; f == nil || f.Code != 3 || results[2].Err != nil {
+		t.Errorf("result 2: expected fault code 3, got %+v", results[2])
+	}
+	if results[3].Err == nil || results[3].Fault != nil || results[3].Value != nil {
+		t.Errorf("result 3: expected error, got %+v", results[3])
+	}
+
+	if n := maxRunning.Load(); n > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got %d", n)
+	}
+}
+
+func TestCallBatchEmpty(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClientWithOptions("http://localhost/RPC2")
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	if results := client.CallBatch(context.Background(), nil); len(results) != 0 {
+		t.Fatalf("expected no results, got %v", results)
+	}
+}
@@ -1,29 +1,491 @@
 package xmlrpc
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 )
 
+// ErrResponseTooLarge is returned when a response body exceeds the limit set
+// with [WithMaxResponseSize].
+var ErrResponseTooLarge = errors.New("xmlrpc: response too large")
+
+// Errors returned by calls wrap one of these sentinels to report the stage of
+// the call that failed, e.g. errors.Is(err, ErrTransport). The underlying
+// error is wrapped as well and can be inspected with [errors.As]. Faults,
+// [HTTPError]s for non-2xx status codes, invalid replies and context errors
+// while waiting to retry are returned without a stage.
+var (
+	// ErrEncode reports that the request could not be built, e.g. because
+	// the arguments could not be encoded or the [RequestSigner] failed.
+	ErrEncode = errors.New("xmlrpc: encoding request failed")
+	// ErrTransport reports that the request could not be sent or no
+	// response was received.
+	ErrTransport = errors.New("xmlrpc: sending request failed")
+	// ErrDecode reports that the response could not be read or decoded, or
+	// lacks a header required with [WithRequiredResponseHeaders].
+	ErrDecode = errors.New("xmlrpc: decoding response failed")
+)
+
+// stageError wraps err with the sentinel of the call stage it occurred in.
+// Its message is that of err, so wrapping does not change the error text.
+type stageError struct {
+	stage error
+	err   error
+}
+
+func (e *stageError) Error() string { return e.err.Error() }
+
+func (e *stageError) Unwrap() []error { return []error{e.stage, e.err} }
+
+// wrapStage wraps err with stage, unless err is nil, already carries a stage
+// or is a [FaultError], which is a regular result of a call.
+func wrapStage(stage, err error) error {
+	var se *stageError
+	if err == nil || errors.As(err, &se) {
+		return err
+	}
+	if _, ok := err.(FaultError); ok {
+		return err
+	}
+	return &stageError{stage: stage, err: err}
+}
+
 // Call invokes the named method, waits for it to complete, and returns its error status.
-// This is equivalent to CallContext with [context.Background].
+// This is equivalent to CallContext with [context.Background], so the call
+// cannot be canceled and is only bounded by the client timeout, set with
+// [WithTimeout] or defaulted by [SetDefaults]; use
+// [Client.CallContext] to cancel calls or set per-call deadlines.
 func (c *Client) Call(serviceMethod string, args any, reply any) error {
 	return c.CallContext(context.Background(), serviceMethod, args, reply)
 }
 
 // CallContext invokes the named method with context support.
-// The context controls cancellation and timeout of the HTTP request.
+// The context controls cancellation and timeout of the HTTP request,
+// including any retries configured with [WithRetry]. If the client was
+// created with [WithHTTPClientTimeout], the earlier of that timeout and the
+// context deadline ends the call.
+//
+// reply must be a non-nil pointer the result is decoded into, or nil to
+// discard the result; otherwise CallContext fails without sending a request.
 func (c *Client) CallContext(ctx context.Context, serviceMethod string, args any, reply any) error {
-	httpRequest, err := NewRequestContext(ctx, c.url.String(), serviceMethod, args)
+	return c.callContext(ctx, serviceMethod, args, reply, nil, nil)
+}
+
+// callOptions holds configuration for a single call.
+type callOptions struct {
+	headers http.Header
+}
+
+// CallOption configures a single call made with [Client.CallWithOptions].
+type CallOption func(*callOptions)
+
+// WithCallHeader adds a header to a single call. Headers added for a call
+// replace client-level headers with the same key, including those set with
+// [WithHeader] and [WithAccept], for that call only.
+// Can be used multiple times to add multiple headers or values.
+func WithCallHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		if o.headers == nil {
+			o.headers = make(http.Header)
+		}
+		o.headers.Add(key, value)
+	}
+}
+
+// CallWithOptions invokes the named method like [Client.CallContext], applying
+// the given options to this call only, e.g. per-call headers such as a request
+// ID or idempotency key.
+func (c *Client) CallWithOptions(
+	ctx context.Context,
+	serviceMethod string,
+	args any,
+	reply any,
+	opts ...CallOption,
+) error {
+	var options callOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return c.callContext(ctx, serviceMethod, args, reply, nil, &options)
+}
+
+// CallResponse describes the HTTP response of a call made with [Client.CallFull].
+type CallResponse struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Header holds the HTTP response headers.
+	Header http.Header
+	// Raw is the response body after removing any content encoding.
+	Raw []byte
+}
+
+// CallFull invokes the named method like [Client.CallContext] and also returns
+// the HTTP response of the call. The response is returned whenever one was
+// received, including for faults and non-2xx status codes, so callers can
+// inspect headers such as Retry-After. With [WithRetry] it describes the
+// response of the last attempt.
+func (c *Client) CallFull(ctx context.Context, method string, args any, reply any) (*CallResponse, error) {
+	var resp CallResponse
+	err := c.callContext(ctx, method, args, reply, &resp, nil)
+	if resp.Header == nil {
+		return nil, err
+	}
+	return &resp, err
+}
+
+// CallInfo describes a completed call. It is passed to the function set
+// with [WithLogger].
+type CallInfo struct {
+	// Method is the name of the called method.
+	Method string
+	// Duration is the time the call took, including any retries.
+	Duration time.Duration
+	// StatusCode is the HTTP status code of the last response, or 0 if no
+	// response was received.
+	StatusCode int
+	// RequestSize is the size of the last request body in bytes as sent,
+	// or -1 if unknown, e.g. for streamed requests.
+	RequestSize int64
+	// ResponseSize is the number of response body bytes read, before
+	// removing any content encoding.
+	ResponseSize int64
+	// Fault is the fault returned by the server, if any.
+	Fault *FaultError
+	// Err is the error returned by the call, including faults.
+	Err error
+}
+
+// callContext performs a call and reports it to the logger, if any.
+// If resp is non-nil, it is filled with the HTTP response of each attempt.
+// opts may be nil.
+func (c *Client) callContext(
+	ctx context.Context,
+	serviceMethod string,
+	args any,
+	reply any,
+	resp *CallResponse,
+	opts *callOptions,
+) error {
+	if err := checkReply(reply); err != nil {
+		return err
+	}
+	if c.callTimeout > 0 {
+		// The derived context keeps the earlier of both deadlines.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.callTimeout)
+		defer cancel()
+	}
+	if c.logger == nil {
+		return c.retryCall(ctx, serviceMethod, args, reply, resp, opts, nil)
+	}
+
+	info := CallInfo{Method: serviceMethod}
+	start := time.Now()
+	err := c.retryCall(ctx, serviceMethod, args, reply, resp, opts, &info)
+	info.Duration = time.Since(start)
+	info.Err = err
+	var fault FaultError
+	if errors.As(err, &fault) {
+		info.Fault = &fault
+	}
+	c.logger(info)
+	return err
+}
+
+// checkReply returns an error unless reply can be decoded into: nil, which
+// discards the result, a non-nil pointer or an [arrayStream].
+func checkReply(reply any) error {
+	if reply == nil {
+		return nil
+	}
+	if _, ok := reply.(arrayStream); ok {
+		return nil
+	}
+	if val := reflect.ValueOf(reply); val.Kind() != reflect.Pointer || val.IsNil() {
+		return fmt.Errorf("xmlrpc: reply must be a non-nil pointer, got %T", reply)
+	}
+	return nil
+}
+
+// retryCall runs the retry loop of a call. If info is non-nil, it is filled
+// with the details of each attempt.
+func (c *Client) retryCall(
+	ctx context.Context,
+	serviceMethod string,
+	args any,
+	reply any,
+	resp *CallResponse,
+	opts *callOptions,
+	info *CallInfo,
+) error {
+	for attempt := 1; ; attempt++ {
+		retry, err := c.call(ctx, serviceMethod, args, reply, resp, opts, info)
+		if err == nil || !retry || attempt >= c.retryAttempts {
+			return err
+		}
+
+		var delay time.Duration
+		if c.retryBackoff != nil {
+			delay = c.retryBackoff(attempt)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// CallTyped invokes the named method using [Client.CallContext] and decodes
+// the result into a new value of type T. On error, the zero value of T is returned.
+func CallTyped[T any](ctx context.Context, c *Client, method string, args any) (T, error) {
+	var reply T
+	if err := c.CallContext(ctx, method, args, &reply); err != nil {
+		var zero T
+		return zero, err
+	}
+	return reply, nil
+}
+
+// CallChan invokes the named method using [Client.CallContext] and decodes
+// the elements of its array result into out as they are read from the
+// response, so large results can be consumed without holding them in memory.
+// Sending on out blocks until the element is received or ctx is done.
+//
+// CallChan closes out exactly once before returning, also on error. It returns
+// a [FaultError] if the server returned a fault, or any transport or decoding
+// error, including one found after some elements have already been sent.
+func CallChan[T any](ctx context.Context, c *Client, method string, args any, out chan<- T) error {
+	defer close(out)
+
+	stream := arrayStream(func(dec *decoder) error {
+		var v T
+		if err := dec.decodeValue(reflect.ValueOf(&v).Elem()); err != nil {
+			return err
+		}
+
+		select {
+		case out <- v:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	return c.CallContext(ctx, method, args, stream)
+}
+
+// CallStream invokes the named method using [Client.CallContext] and calls
+// each for every element of its array result as it is read from the response,
+// so large results can be processed without holding them in memory. each is
+// passed a decode function that decodes the current element into v, which
+// must be a non-nil pointer; decode may be called at most once per element,
+// and elements each does not decode are skipped.
+//
+// If each returns an error, the call stops and returns it. CallStream returns
+// a [FaultError] if the server returned a fault, or any transport or decoding
+// error, including one found after some elements have already been processed.
+func (c *Client) CallStream(
+	ctx context.Context,
+	method string,
+	args any,
+	each func(decode func(v any) error) error,
+) error {
+	stream := arrayStream(func(dec *decoder) error {
+		var decoded bool
+		var decodeErr error
+		decode := func(v any) error {
+			if decoded {
+				return errors.New("xmlrpc: array element already decoded")
+			}
+			val := reflect.ValueOf(v)
+			if val.Kind() != reflect.Pointer || val.IsNil() {
+				return fmt.Errorf("xmlrpc: decode requires a non-nil pointer, got %T", v)
+			}
+			decoded = true
+			decodeErr = dec.decodeValue(val.Elem())
+			return decodeErr
+		}
+
+		if err := each(decode); err != nil {
+			return err
+		}
+		// A failed decode leaves the decoder mid-element, so the call
+		// cannot continue even if each ignored the error.
+		if decodeErr != nil {
+			return decodeErr
+		}
+		if !decoded {
+			return dec.Skip()
+		}
+		return nil
+	})
+	return c.CallContext(ctx, method, args, stream)
+}
+
+// CallWithResult invokes the named method like [Client.CallContext] but
+// reports the outcome in three distinct values instead of a single error.
+//
+// If reply is nil, the result is decoded into an any and returned as value;
+// otherwise it is decoded into reply and reply itself is returned as value.
+//
+// The outcomes are checked in order: if the call failed before a valid
+// response was decoded, err is non-nil and value and fault are nil. If the
+// server returned a fault, fault is non-nil and value and err are nil.
+// Otherwise value holds the result and fault and err are nil.
+func (c *Client) CallWithResult(
+	ctx context.Context,
+	serviceMethod string,
+	args any,
+	reply any,
+) (value any, fault *FaultError, err error) {
+	var result any
+	target := reply
+	if target == nil {
+		target = &result
+	}
+
+	err = c.CallContext(ctx, serviceMethod, args, target)
+	if f, ok := err.(FaultError); ok {
+		return nil, &f, nil
+	}
 	if err != nil {
+		return nil, nil, err
+	}
+
+	if reply == nil {
+		return result, nil, nil
+	}
+	return reply, nil, nil
+}
+
+// MethodResult holds the decoded response of a call made with
+// [Client.CallRaw], leaving the fault or success decision to the caller.
+type MethodResult struct {
+	raw    []byte
+	fault  *FaultError
+	decode decodeOptions
+}
+
+// Fault returns the fault returned by the server, or nil if the call
+// succeeded.
+func (r *MethodResult) Fault() *FaultError {
+	return r.fault
+}
+
+// Decode decodes the value of the response into v. For a fault, this is the
+// fault struct, including any members beyond faultCode and faultString.
+func (r *MethodResult) Decode(v any) error {
+	if err := checkReply(v); err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	dec := newDecoder(bytes.NewReader(r.raw), r.decode)
+	if _, err := dec.readResponseStart(); err != nil {
 		return err
 	}
+	return dec.unmarshal(v)
+}
+
+// CallRaw invokes the named method like [Client.CallContext] but does not
+// convert a fault into an error. Instead, the fault is available from the
+// returned [MethodResult] along with the value of the response. An error is
+// returned only if the call failed before a valid response was decoded.
+func (c *Client) CallRaw(ctx context.Context, method string, args any) (*MethodResult, error) {
+	var resp CallResponse
+	err := c.callContext(ctx, method, args, nil, &resp, nil)
+	result := &MethodResult{raw: resp.Raw, decode: c.decode}
+	if f, ok := err.(FaultError); ok {
+		result.fault = &f
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// call performs a single attempt of a call. It reports whether the attempt
+// failed with an error that may be retried. If cr is non-nil, it is filled
+// with the HTTP response and if info is non-nil, with the details of the
+// attempt. opts may be nil.
+func (c *Client) call(
+	ctx context.Context,
+	serviceMethod string,
+	args any,
+	reply any,
+	cr *CallResponse,
+	opts *callOptions,
+	info *CallInfo,
+) (bool, error) {
+	if cr != nil {
+		*cr = CallResponse{}
+	}
+	if info != nil {
+		info.StatusCode, info.RequestSize, info.ResponseSize = 0, 0, 0
+	}
+
+	var httpRequest *http.Request
+	var err error
+	if c.streamRequests {
+		httpRequest, err = newStreamingRequest(
+			ctx, c.url.String(), serviceMethod, args, c.encode, c.compress,
+		)
+	} else {
+		httpRequest, err = newRequest(ctx, c.url.String(), serviceMethod, args, c.encode, c.compress)
+	}
+	if err != nil {
+		return false, wrapStage(ErrEncode, err)
+	}
 
 	for key, values := range c.headers {
 		for _, value := range values {
 			httpRequest.Header.Add(key, value)
 		}
 	}
+	if c.accept != "" {
+		httpRequest.Header.Set("Accept", c.accept)
+	} else if httpRequest.Header.Get("Accept") == "" {
+		httpRequest.Header.Set("Accept", "text/xml")
+	}
+	if c.acceptEncoding != "" {
+		httpRequest.Header.Set("Accept-Encoding", c.acceptEncoding)
+	}
+	if c.acceptLanguage != "" {
+		httpRequest.Header.Set("Accept-Language", c.acceptLanguage)
+	}
+	if c.userAgent != "" && len(httpRequest.Header.Values("User-Agent")) == 0 {
+		httpRequest.Header.Set("User-Agent", c.userAgent)
+	}
+	if opts != nil {
+		for key, values := range opts.headers {
+			httpRequest.Header[key] = slices.Clone(values)
+		}
+	}
+
+	if c.signer != nil {
+		if err := c.signRequest(ctx, httpRequest, serviceMethod); err != nil {
+			return false, wrapStage(ErrEncode, err)
+		}
+	}
+	if info != nil {
+		info.RequestSize = httpRequest.ContentLength
+	}
 
 	if c.cookies != nil {
 		for _, cookie := range c.cookies.Cookies(c.url) {
@@ -31,22 +493,178 @@ func (c *Client) CallContext(ctx context.Context, serviceMethod string, args any
 		}
 	}
 
+	if c.requestHook != nil {
+		c.requestHook(ctx, httpRequest)
+	}
 	resp, err := c.httpClient.Do(httpRequest)
+	if c.responseHook != nil {
+		c.responseHook(ctx, resp, err)
+	}
 	if err != nil {
-		return err
+		return ctx.Err() == nil, wrapStage(ErrTransport, err)
 	}
 	defer resp.Body.Close()
 
+	if info != nil {
+		info.StatusCode = resp.StatusCode
+		resp.Body = &countingReader{ReadCloser: resp.Body, n: &info.ResponseSize}
+	}
+
 	if c.cookies != nil {
 		c.cookies.SetCookies(c.url, resp.Cookies())
 	}
 
+	if cr != nil {
+		*cr = CallResponse{StatusCode: resp.StatusCode, Header: resp.Header}
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("xmlrpc: unexpected status code %d", resp.StatusCode)
+		httpErr := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+		if body, err := decompressBody(resp); err == nil {
+			httpErr.Body, _ = io.ReadAll(c.limitBody(body))
+			body.Close()
+		}
+		if cr != nil {
+			cr.Raw = httpErr.Body
+		}
+		return slices.Contains(c.retryStatusCodes, resp.StatusCode), httpErr
+	}
+
+	for _, key := range c.requiredResponseHeaders {
+		if len(resp.Header.Values(key)) == 0 {
+			return false, wrapStage(ErrDecode, fmt.Errorf("xmlrpc: missing required response header %q", key))
+		}
+	}
+
+	body, err := decompressBody(resp)
+	if err != nil {
+		return false, wrapStage(ErrDecode, err)
+	}
+	defer body.Close()
+
+	// The body is decoded while it is read, so large responses are never held
+	// in memory as a whole unless the raw body was requested.
+	r := c.limitBody(body)
+	if cr != nil {
+		// Some servers reset the connection right after sending the body.
+		// Ignore read errors once a complete methodResponse was received.
+		cr.Raw, err = io.ReadAll(r)
+		if err != nil && (errors.Is(err, ErrResponseTooLarge) || !completeResponse(cr.Raw)) {
+			return false, wrapStage(ErrDecode, err)
+		}
+		r = bytes.NewReader(cr.Raw)
 	}
 
 	if reply == nil {
 		reply = new(any)
 	}
-	return unmarshalResponse(resp.Body, reply)
+	return false, wrapStage(ErrDecode, newDecoder(r, c.decode).unmarshalResponse(reply))
+}
+
+// countingReader counts the bytes read from a response body.
+type countingReader struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
+
+// completeResponse reports whether data holds a well-formed document up to
+// and including the closing </methodResponse>.
+func completeResponse(data []byte) bool {
+	dec := newDecoder(bytes.NewReader(data), decodeOptions{})
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if t, ok := tok.(xml.EndElement); ok && t.Name.Local == "methodResponse" {
+			return true
+		}
+	}
+}
+
+// limitBody returns body limited to the maximum response size, if any.
+func (c *Client) limitBody(body io.Reader) io.Reader {
+	if c.maxResponseSize <= 0 {
+		return body
+	}
+	return &limitedReader{r: body, remaining: c.maxResponseSize}
+}
+
+// limitedReader reads from r until more than remaining bytes have been read,
+// then fails with [ErrResponseTooLarge].
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	// Read one byte beyond the limit to detect bodies that exceed it.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// signRequest passes the encoded body of req to the request signer and
+// sets the returned headers on req.
+func (c *Client) signRequest(ctx context.Context, req *http.Request, serviceMethod string) error {
+	rc, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	body, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	header, err := c.signer(ctx, serviceMethod, body)
+	if err != nil {
+		return fmt.Errorf("xmlrpc: failed to sign request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	return nil
+}
+
+// decompressBody returns a reader for the response body that undoes the
+// gzip or deflate Content-Encoding, if any.
+func decompressBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return io.NopCloser(resp.Body), nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("xmlrpc: failed to read gzip response: %w", err)
+		}
+		return r, nil
+	case "deflate":
+		r, err := zlib.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("xmlrpc: failed to read deflate response: %w", err)
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf(
+			"xmlrpc: unsupported response content encoding %q",
+			resp.Header.Get("Content-Encoding"),
+		)
+	}
 }
@@ -0,0 +1,121 @@
+package xmlrpc
+
+import (
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWithTCPKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	// sockopts returns SO_KEEPALIVE, TCP_KEEPIDLE and TCP_KEEPINTVL of a
+	// connection dialed by the transport of client.
+	sockopts := func(t *testing.T, client *Client) (keepAlive, idle, interval int) {
+		t.Helper()
+
+		transport, ok := client.httpClient.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("expected *http.Transport, got %T", client.httpClient.Transport)
+		}
+		conn, err := transport.DialContext(t.Context(), "tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		raw, err := conn.(*net.TCPConn).SyscallConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sockErr error
+		if err := raw.Control(func(fd uintptr) {
+			keepAlive, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+			if sockErr == nil {
+				idle, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+			}
+			if sockErr == nil {
+				interval, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL)
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if sockErr != nil {
+			t.Fatal(sockErr)
+		}
+		return keepAlive, idle, interval
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		client, err := NewClientWithOptions("http://"+ln.Addr().String(), WithTCPKeepAlive(7*time.Second))
+		if err != nil {
+			t.Fatalf("NewClientWithOptions error: %v", err)
+		}
+		defer client.Close()
+
+		if keepAlive, idle, interval := sockopts(t, client); keepAlive != 1 || idle != 7 || interval != 7 {
+			t.Fatalf("expected keep-alive after 7s every 7s, got SO_KEEPALIVE=%d TCP_KEEPIDLE=%d TCP_KEEPINTVL=%d",
+				keepAlive, idle, interval)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		client, err := NewClientWithOptions("http://"+ln.Addr().String(), WithTCPKeepAlive(-1))
+		if err != nil {
+			t.Fatalf("NewClientWithOptions error: %v", err)
+		}
+		defer client.Close()
+
+		if keepAlive, _, _ := sockopts(t, client); keepAlive != 0 {
+			t.Fatalf("expected keep-alive disabled, got SO_KEEPALIVE=%d", keepAlive)
+		}
+	})
+
+	t.Run("custom_transport", func(t *testing.T) {
+		t.Parallel()
+
+		transport := &http.Transport{}
+		client, err := NewClientWithOptions("http://"+ln.Addr().String(),
+			WithTransport(transport),
+			WithTCPKeepAlive(7*time.Second),
+		)
+		if err != nil {
+			t.Fatalf("NewClientWithOptions error: %v", err)
+		}
+		defer client.Close()
+
+		if client.httpClient.Transport != transport {
+			t.Fatal("expected custom transport to be used unchanged")
+		}
+		if transport.DialContext != nil {
+			t.Fatal("expected custom transport dialer to be left unset")
+		}
+	})
+}
@@ -1,12 +1,26 @@
 package xmlrpc
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -137,6 +151,180 @@ func TestCallContext(t *testing.T) {
 	}
 }
 
+func TestCallWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL, WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	var result string
+	err = client.Call("test.method", nil, &result)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "Client.Timeout exceeded") {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Fatalf("call took %v, expected it to time out after 50ms", elapsed)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	SetDefaults(Config{Timeout: 50 * time.Millisecond, UserAgent: "defaults/1.0"})
+	t.Cleanup(func() { SetDefaults(Config{}) })
+
+	// The timed-out slow request may still be handled while later requests
+	// arrive, so the user agent is guarded by a mutex.
+	var mu sync.Mutex
+	var receivedUserAgent string
+	userAgent := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return receivedUserAgent
+	}
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("slow") {
+			select {
+			case <-time.After(300 * time.Millisecond):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		mu.Lock()
+		receivedUserAgent = r.Header.Get("User-Agent")
+		mu.Unlock()
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Error(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var result string
+	if err := client.Call("test.method", nil, &result); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if got := userAgent(); got != "defaults/1.0" {
+		t.Errorf("User-Agent: expected 'defaults/1.0', got '%s'", got)
+	}
+
+	slowClient, err := NewClientWithOptions(ts.URL + "?slow")
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer slowClient.Close()
+
+	if err := slowClient.Call("test.method", nil, &result); err == nil {
+		t.Fatal("expected default timeout to apply, got nil error")
+	}
+
+	override, err := NewClientWithOptions(ts.URL, WithHeader("User-Agent", "override/2.0"))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer override.Close()
+
+	if err := override.Call("test.method", nil, &result); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if got := userAgent(); got != "override/2.0" {
+		t.Errorf("User-Agent: expected 'override/2.0', got '%s'", got)
+	}
+}
+
+func TestCallTyped(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var value string
+		switch {
+		case strings.Contains(string(body), "test.struct"):
+			value = `<struct><member><name>Title</name><value><string>War and Piece</string></value></member><member><name>Amount</name><value><int>20</int></value></member></struct>`
+		case strings.Contains(string(body), "test.slice"):
+			value = `<array><data><value><int>1</int></value><value><int>2</int></value></data></array>`
+		case strings.Contains(string(body), "test.fault"):
+			_, _ = io.WriteString(w, `<?xml version="1.0"?><methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>fault</string></value></member></struct></value></fault></methodResponse>`)
+			return
+		default:
+			value = `<int>42</int>`
+		}
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value>`+value+`</value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	t.Run("scalar", func(t *testing.T) {
+		v, err := CallTyped[int](t.Context(), client, "test.scalar", nil)
+		if err != nil || v != 42 {
+			t.Fatalf("expected 42, got %v (err: %v)", v, err)
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		v, err := CallTyped[book](t.Context(), client, "test.struct", nil)
+		if err != nil || v != (book{"War and Piece", 20}) {
+			t.Fatalf("unexpected result %+v (err: %v)", v, err)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		v, err := CallTyped[*book](t.Context(), client, "test.struct", nil)
+		if err != nil || v == nil || *v != (book{"War and Piece", 20}) {
+			t.Fatalf("unexpected result %+v (err: %v)", v, err)
+		}
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		v, err := CallTyped[[]int](t.Context(), client, "test.slice", nil)
+		if err != nil || len(v) != 2 || v[0] != 1 || v[1] != 2 {
+			t.Fatalf("unexpected result %v (err: %v)", v, err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		v, err := CallTyped[*book](t.Context(), client, "test.fault", nil)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if v != nil {
+			t.Fatalf("expected zero value, got %+v", v)
+		}
+	})
+}
+
 func TestCallWithHeaders(t *testing.T) {
 	t.Parallel()
 
@@ -173,12 +361,12 @@ func TestCallWithHeaders(t *testing.T) {
 	}
 }
 
-func TestCallWithBasicAuth(t *testing.T) {
+func TestCallWithOptionsHeaders(t *testing.T) {
 	t.Parallel()
 
-	var receivedAuth string
+	var receivedHeaders http.Header
 	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		receivedAuth = r.Header.Get("Authorization")
+		receivedHeaders = r.Header.Clone()
 		if _, err := io.WriteString(
 			w,
 			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
@@ -187,137 +375,2053 @@ func TestCallWithBasicAuth(t *testing.T) {
 		}
 	})
 
-	client, err := NewClientWithOptions(ts.URL, WithBasicAuth("testuser", "testpass"))
+	client, err := NewClientWithOptions(ts.URL,
+		WithHeader("X-Request-Id", "client"),
+		WithHeader("X-Tenant", "acme"),
+		WithHeader("User-Agent", "client-agent"),
+	)
 	if err != nil {
 		t.Fatalf("NewClientWithOptions error: %v", err)
 	}
 	defer client.Close()
 
 	var result string
-	if err := client.Call("test.method", nil, &result); err != nil {
-		t.Fatalf("Call error: %v", err)
+	if err := client.CallWithOptions(t.Context(), "test.method", nil, &result,
+		WithCallHeader("X-Request-Id", "req-1"),
+		WithCallHeader("Idempotency-Key", "key-1"),
+		WithCallHeader("Idempotency-Key", "key-2"),
+		WithCallHeader("User-Agent", "call-agent"),
+	); err != nil {
+		t.Fatalf("CallWithOptions error: %v", err)
+	}
+
+	if got := receivedHeaders.Values("X-Request-Id"); !reflect.DeepEqual(got, []string{"req-1"}) {
+		t.Errorf("X-Request-Id: expected [req-1], got %v", got)
+	}
+	if got := receivedHeaders.Values("Idempotency-Key"); !reflect.DeepEqual(got, []string{"key-1", "key-2"}) {
+		t.Errorf("Idempotency-Key: expected [key-1 key-2], got %v", got)
+	}
+	if got := receivedHeaders.Get("User-Agent"); got != "call-agent" {
+		t.Errorf("User-Agent: expected 'call-agent', got '%s'", got)
+	}
+	if got := receivedHeaders.Get("X-Tenant"); got != "acme" {
+		t.Errorf("X-Tenant: expected 'acme', got '%s'", got)
 	}
 
-	// Basic auth header should be "Basic dGVzdHVzZXI6dGVzdHBhc3M=" (base64 of "testuser:testpass")
-	if receivedAuth != "Basic dGVzdHVzZXI6dGVzdHBhc3M=" {
-		t.Errorf("expected basic auth header, got '%s'", receivedAuth)
+	// Per-call headers must not leak into subsequent calls.
+	if err := client.CallContext(t.Context(), "test.method", nil, &result); err != nil {
+		t.Fatalf("CallContext error: %v", err)
+	}
+	if got := receivedHeaders.Values("X-Request-Id"); !reflect.DeepEqual(got, []string{"client"}) {
+		t.Errorf("X-Request-Id: expected [client], got %v", got)
+	}
+	if got := receivedHeaders.Get("Idempotency-Key"); got != "" {
+		t.Errorf("Idempotency-Key: expected none, got '%s'", got)
+	}
+	if got := receivedHeaders.Get("User-Agent"); got != "client-agent" {
+		t.Errorf("User-Agent: expected 'client-agent', got '%s'", got)
 	}
 }
 
-func TestCallBadStatus(t *testing.T) {
+func TestCallWithLogger(t *testing.T) {
 	t.Parallel()
 
-	statusCodes := []int{400, 401, 403, 404, 500, 502, 503}
+	const okResponse = `<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`
+	const faultResponse = `<?xml version="1.0"?><methodResponse><fault><value><struct>` +
+		`<member><name>faultCode</name><value><int>42</int></value></member>` +
+		`<member><name>faultString</name><value><string>boom</string></value></member>` +
+		`</struct></value></fault></methodResponse>`
 
-	for _, code := range statusCodes {
-		t.Run(http.StatusText(code), func(t *testing.T) {
-			t.Parallel()
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		resp := okResponse
+		if body, _ := io.ReadAll(r.Body); strings.Contains(string(body), "fail") {
+			resp = faultResponse
+		}
+		if _, err := io.WriteString(w, resp); err != nil {
+			t.Fatal(err)
+		}
+	})
 
-			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "error", code)
-			})
+	var infos []CallInfo
+	client, err := NewClientWithOptions(ts.URL, WithLogger(func(info CallInfo) {
+		infos = append(infos, info)
+	}))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
 
-			client, err := NewClientWithOptions(ts.URL)
-			if err != nil {
-				t.Fatalf("NewClientWithOptions error: %v", err)
-			}
-			defer client.Close()
+	var result string
+	if err := client.Call("test.method", nil, &result); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if err := client.Call("test.fail", nil, &result); err == nil {
+		t.Fatal("expected fault, got nil")
+	}
 
-			var result string
-			err = client.Call("test.method", nil, &result)
-			if err == nil {
-				t.Fatal("expected error, got nil")
-			}
-			if !strings.Contains(err.Error(), string(rune('0'+code/100))) {
-				t.Fatalf("expected status code %d in error, got: %v", code, err)
-			}
-		})
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 logged calls, got %d", len(infos))
+	}
+
+	info := infos[0]
+	if info.Method != "test.method" {
+		t.Errorf("Method: expected 'test.method', got %q", info.Method)
+	}
+	if info.Duration <= 0 {
+		t.Errorf("Duration: expected > 0, got %v", info.Duration)
+	}
+	if info.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode: expected 200, got %d", info.StatusCode)
+	}
+	if info.RequestSize <= 0 {
+		t.Errorf("RequestSize: expected > 0, got %d", info.RequestSize)
+	}
+	if info.ResponseSize != int64(len(okResponse)) {
+		t.Errorf("ResponseSize: expected %d, got %d", len(okResponse), info.ResponseSize)
+	}
+	if info.Err != nil || info.Fault != nil {
+		t.Errorf("expected no error, got Err=%v Fault=%v", info.Err, info.Fault)
+	}
+
+	info = infos[1]
+	if info.Method != "test.fail" || info.Fault == nil || info.Fault.Code != 42 || info.Err == nil {
+		t.Errorf("expected fault 42 for test.fail, got %+v", info)
 	}
 }
 
-func TestCallFaultResponse(t *testing.T) {
+func TestCallWithHooks(t *testing.T) {
 	t.Parallel()
 
+	type ctxKey struct{}
+
+	var receivedHeaders http.Header
 	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
-			<methodResponse>
-				<fault>
-					<value>
-						<struct>
-							<member>
-								<name>faultCode</name>
-								<value><int>4</int></value>
-							</member>
-							<member>
-								<name>faultString</name>
-								<value><string>Too many parameters.</string></value>
-							</member>
-						</struct>
-					</value>
-				</fault>
-			</methodResponse>`); err != nil {
+		receivedHeaders = r.Header.Clone()
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
 			t.Fatal(err)
 		}
 	})
 
-	client, err := NewClientWithOptions(ts.URL)
-	if err != nil {
-		t.Fatalf("NewClientWithOptions error: %v", err)
+	var requests, responses int
+	var hookReq *http.Request
+	var hookResp *http.Response
+	var hookErr error
+	newClient := func(url string) *Client {
+		client, err := NewClientWithOptions(url,
+			WithHeader("X-Client", "1"),
+			WithRequestHook(func(ctx context.Context, req *http.Request) {
+				requests++
+				if ctx.Value(ctxKey{}) != "span" {
+					t.Errorf("request hook: unexpected context")
+				}
+				if req.Header.Get("X-Client") != "1" {
+					t.Errorf("request hook: client headers not applied")
+				}
+				req.Header.Set("Traceparent", "00-trace-span-01")
+				hookReq = req
+			}),
+			WithResponseHook(func(ctx context.Context, resp *http.Response, err error) {
+				responses++
+				if ctx.Value(ctxKey{}) != "span" {
+					t.Errorf("response hook: unexpected context")
+				}
+				hookResp, hookErr = resp, err
+			}),
+		)
+		if err != nil {
+			t.Fatalf("NewClientWithOptions error: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
 	}
-	defer client.Close()
+
+	ctx := context.WithValue(t.Context(), ctxKey{}, "span")
 
 	var result string
-	err = client.Call("test.method", nil, &result)
-	if err == nil {
-		t.Fatal("expected fault error, got nil")
+	if err := newClient(ts.URL).CallContext(ctx, "test.method", nil, &result); err != nil {
+		t.Fatalf("CallContext error: %v", err)
+	}
+	if requests != 1 || responses != 1 {
+		t.Fatalf("expected each hook to fire once, got %d requests and %d responses", requests, responses)
+	}
+	if got := receivedHeaders.Get("Traceparent"); got != "00-trace-span-01" {
+		t.Errorf("Traceparent: expected header from request hook, got '%s'", got)
+	}
+	if hookResp == nil || hookResp.StatusCode != http.StatusOK || hookResp.Request != hookReq || hookErr != nil {
+		t.Errorf("response hook: unexpected arguments resp=%v err=%v", hookResp, hookErr)
+	}
+	if result != "ok" {
+		t.Errorf("expected 'ok', got %q", result)
 	}
 
-	fault, ok := err.(FaultError)
-	if !ok {
-		t.Fatalf("expected FaultError, got %T: %v", err, err)
+	// A closed server makes the transport fail.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	err := newClient(closed.URL).CallContext(ctx, "test.method", nil, &result)
+	if err == nil {
+		t.Fatal("expected transport error, got nil")
 	}
-	if fault.Code != 4 {
-		t.Errorf("expected fault code 4, got %d", fault.Code)
+	if requests != 2 || responses != 2 {
+		t.Fatalf("expected each hook to fire twice, got %d requests and %d responses", requests, responses)
 	}
-	if !strings.Contains(fault.String, "Too many parameters") {
-		t.Errorf("expected 'Too many parameters' in fault string, got %q", fault.String)
+	if hookResp != nil || hookErr == nil || !errors.Is(err, hookErr) {
+		t.Errorf("response hook: expected transport error, got resp=%v err=%v", hookResp, hookErr)
 	}
 }
 
-func TestCallBadStatusRecovery(t *testing.T) {
+func TestCallInvalidReply(t *testing.T) {
 	t.Parallel()
 
-	callCount := 0
+	var requests atomic.Int32
 	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		callCount++
-		if callCount == 1 {
-			http.Error(w, "bad status", http.StatusInternalServerError)
-			return
-		}
+		requests.Add(1)
 		if _, err := io.WriteString(
 			w,
-			`<?xml version="1.0"?><methodResponse><params><param><value><struct></struct></value></param></params></methodResponse>`,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
 		); err != nil {
 			t.Fatal(err)
 		}
 	})
 
-	client, err := NewClient(ts.URL, nil)
+	client, err := NewClientWithOptions(ts.URL)
 	if err != nil {
-		t.Fatalf("NewClient error: %v", err)
+		t.Fatalf("NewClientWithOptions error: %v", err)
 	}
 	defer client.Close()
 
-	var result any
+	tests := []struct {
+		name  string
+		reply any
+		want  string
+	}{
+		{"non_pointer", "", "xmlrpc: reply must be a non-nil pointer, got string"},
+		{"typed_nil_pointer", (*string)(nil), "xmlrpc: reply must be a non-nil pointer, got *string"},
+	}
 
-	// First call should fail
-	if err := client.Call("method", nil, &result); err == nil {
-		t.Fatal("expected error on first call")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.Call("test.method", nil, tt.reply)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("expected no requests for invalid replies, got %d", n)
 	}
 
-	// Second call should succeed
-	if err := client.Call("method", nil, &result); err != nil {
-		t.Fatalf("expected success on second call, got: %v", err)
+	// A nil reply discards the result.
+	if err := client.Call("test.method", nil, nil); err != nil {
+		t.Fatalf("Call with nil reply error: %v", err)
+	}
+}
+
+func TestCallWithDisableKeepAlives(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantClose bool
+	}{
+		{"default", nil, false},
+		{"disabled", []Option{WithDisableKeepAlives()}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var closes atomic.Int32
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Close {
+					closes.Add(1)
+				}
+				if _, err := io.WriteString(
+					w,
+					`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+				); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			if transport, ok := client.httpClient.Transport.(*http.Transport); !ok {
+				t.Fatalf("expected *http.Transport, got %T", client.httpClient.Transport)
+			} else if transport.DisableKeepAlives != tt.wantClose {
+				t.Fatalf("expected DisableKeepAlives %v, got %v", tt.wantClose, transport.DisableKeepAlives)
+			}
+
+			for range 2 {
+				var result string
+				if err := client.Call("test.method", nil, &result); err != nil {
+					t.Fatalf("Call error: %v", err)
+				}
+			}
+			if got := closes.Load() == 2; got != tt.wantClose {
+				t.Errorf("expected Connection: close on every request %v, got %d of 2", tt.wantClose, closes.Load())
+			}
+		})
+	}
+}
+
+func TestCallWithTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	// Without the server's certificate the call fails verification.
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	var result string
+	if err := client.Call("test.method", nil, &result); err == nil {
+		t.Fatal("expected certificate error, got nil")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	client, err = NewClientWithOptions(ts.URL, WithTLSConfig(&tls.Config{RootCAs: pool}), WithTCPKeepAlive(time.Minute))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call("test.method", nil, &result); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if result != "ok" {
+		t.Fatalf("expected 'ok', got %q", result)
+	}
+
+	for name, opt := range map[string]Option{
+		"http_client": WithHTTPClient(ts.Client()),
+		"transport":   WithTransport(ts.Client().Transport),
+	} {
+		if _, err := NewClientWithOptions(ts.URL, WithTLSConfig(&tls.Config{RootCAs: pool}), opt); err == nil {
+			t.Errorf("%s: expected error combining options, got nil", name)
+		}
+	}
+}
+
+func TestCallWithUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "xmlrpc.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+
+	var receivedPath string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>RUNNING</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	ts.Listener.Close()
+	ts.Listener = ln
+	ts.Start()
+	defer ts.Close()
+
+	client, err := NewClientWithOptions("http://localhost/RPC2", WithUnixSocket(socket))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var state string
+	if err := client.Call("supervisor.getState", nil, &state); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if state != "RUNNING" {
+		t.Errorf("expected 'RUNNING', got %q", state)
+	}
+	if receivedPath != "/RPC2" {
+		t.Errorf("expected request path '/RPC2', got %q", receivedPath)
+	}
+
+	if _, err := NewClientWithOptions("http://localhost/RPC2", WithUnixSocket(socket), WithTransport(http.DefaultTransport)); err == nil {
+		t.Error("expected error combining WithUnixSocket and WithTransport, got nil")
+	}
+}
+
+func TestCallWithCookies(t *testing.T) {
+	t.Parallel()
+
+	var receivedCookie string
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			receivedCookie = c.Value
+		}
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL,
+		WithCookies(nil, []*http.Cookie{{Name: "session", Value: "abc123"}}),
+	)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call("test.method", nil, nil); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if receivedCookie != "abc123" {
+		t.Errorf("expected seeded session cookie 'abc123', got %q", receivedCookie)
+	}
+
+	if _, err := NewClientWithOptions(ts.URL,
+		WithCookieJar(nil),
+		WithCookies(nil, []*http.Cookie{{Name: "session", Value: "abc123"}}),
+	); err == nil {
+		t.Error("expected error combining WithCookies and a disabled cookie jar, got nil")
+	}
+}
+
+func TestClientCookies(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "xyz789"})
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	if cookies := client.Cookies(); len(cookies) != 0 {
+		t.Fatalf("expected no cookies before the first call, got %v", cookies)
+	}
+	if err := client.Call("test.method", nil, nil); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	cookies := client.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "xyz789" {
+		t.Errorf("expected session cookie 'xyz789', got %v", cookies)
+	}
+
+	disabled, err := NewClientWithOptions(ts.URL, WithCookieJar(nil))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer disabled.Close()
+
+	if err := disabled.Call("test.method", nil, nil); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if cookies := disabled.Cookies(); cookies != nil {
+		t.Errorf("expected nil with cookies disabled, got %v", cookies)
+	}
+}
+
+func TestCallWithBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	var receivedAuth string
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		receivedAuth = r.Header.Get("Authorization")
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"only", []Option{WithBasicAuth("testuser", "testpass")}},
+		{"before_header", []Option{WithBasicAuth("testuser", "testpass"), WithHeader("X-Custom", "value")}},
+		{"after_header", []Option{WithHeader("X-Custom", "value"), WithBasicAuth("testuser", "testpass")}},
+	}
+
+	for _, tt := range tests {
+		client, err := NewClientWithOptions(ts.URL, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: NewClientWithOptions error: %v", tt.name, err)
+		}
+		defer client.Close()
+
+		var result string
+		if err := client.Call("test.method", nil, &result); err != nil {
+			t.Fatalf("%s: Call error: %v", tt.name, err)
+		}
+
+		// Basic auth header should be "Basic dGVzdHVzZXI6dGVzdHBhc3M=" (base64 of "testuser:testpass")
+		if receivedAuth != "Basic dGVzdHVzZXI6dGVzdHBhc3M=" {
+			t.Errorf("%s: expected basic auth header, got '%s'", tt.name, receivedAuth)
+		}
+	}
+}
+
+func TestCallWithAuthorization(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"bearer", []Option{WithBearerToken("abc123")}, []string{"Bearer abc123"}},
+		{"custom", []Option{WithAuthorization("Token xyz")}, []string{"Token xyz"}},
+		{
+			"basic_then_bearer",
+			[]Option{WithBasicAuth("testuser", "testpass"), WithBearerToken("abc123")},
+			[]string{"Bearer abc123"},
+		},
+		{
+			"bearer_then_basic",
+			[]Option{WithBearerToken("abc123"), WithBasicAuth("testuser", "testpass")},
+			[]string{"Basic dGVzdHVzZXI6dGVzdHBhc3M="},
+		},
+		{
+			"header_then_bearer",
+			[]Option{WithHeader("Authorization", "Token old"), WithBearerToken("abc123")},
+			[]string{"Bearer abc123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var receivedAuth []string
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				receivedAuth = r.Header.Values("Authorization")
+				if _, err := io.WriteString(
+					w,
+					`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+				); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			if err := client.Call("test.method", nil, &result); err != nil {
+				t.Fatalf("Call error: %v", err)
+			}
+			if !reflect.DeepEqual(receivedAuth, tt.want) {
+				t.Errorf("expected Authorization %q, got %q", tt.want, receivedAuth)
+			}
+		})
+	}
+}
+
+func TestCallWithAccept(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, "text/xml"},
+		{"custom", []Option{WithAccept("application/xml")}, "application/xml"},
+		{"header", []Option{WithHeader("Accept", "application/json")}, "application/json"},
+		{
+			"overrides_header",
+			[]Option{WithHeader("Accept", "text/plain"), WithAccept("application/vnd.test+xml")},
+			"application/vnd.test+xml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var receivedAccept []string
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				receivedAccept = r.Header.Values("Accept")
+				if _, err := io.WriteString(
+					w,
+					`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+				); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			if err := client.Call("test.method", nil, &result); err != nil {
+				t.Fatalf("Call error: %v", err)
+			}
+
+			if len(receivedAccept) != 1 || receivedAccept[0] != tt.want {
+				t.Errorf("Accept: expected [%s], got %v", tt.want, receivedAccept)
+			}
+		})
+	}
+}
+
+func TestCallWithAcceptLanguage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"none", nil, nil},
+		{"single", []Option{WithAcceptLanguage("en")}, []string{"en;q=1.0"}},
+		{"explicit_quality", []Option{WithAcceptLanguage("en", "de;q=0.8")}, []string{"en;q=1.0, de;q=0.8"}},
+		{"descending", []Option{WithAcceptLanguage("en", "de", "fr")}, []string{"en;q=1.0, de;q=0.9, fr;q=0.8"}},
+		{
+			"overrides_header",
+			[]Option{WithHeader("Accept-Language", "fr"), WithAcceptLanguage("en")},
+			[]string{"en;q=1.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var received []string
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Values("Accept-Language")
+				if _, err := io.WriteString(
+					w,
+					`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+				); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			if err := client.Call("test.method", nil, &result); err != nil {
+				t.Fatalf("Call error: %v", err)
+			}
+
+			if !reflect.DeepEqual(received, tt.want) {
+				t.Errorf("Accept-Language: expected %q, got %q", tt.want, received)
+			}
+		})
+	}
+}
+
+func TestCallWithRequiredResponseHeaders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		values     []string
+		wantErr    bool
+		errContain string
+	}{
+		{"present", []string{"abc123"}, false, ""},
+		{"empty", []string{""}, false, ""},
+		{"missing", nil, true, "X-Signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				for _, v := range tt.values {
+					w.Header().Add("X-Signature", v)
+				}
+				if _, err := io.WriteString(
+					w,
+					`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+				); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, WithRequiredResponseHeaders("X-Signature"))
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			err = client.Call("test.method", nil, &result)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.errContain) {
+					t.Fatalf("expected error containing %q, got: %v", tt.errContain, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCallWithStreamingRequests(t *testing.T) {
+	t.Parallel()
+
+	payload := Base64(strings.Repeat("QUJD", 1<<18))
+
+	var (
+		receivedBody     []byte
+		receivedEncoding []string
+	)
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		receivedEncoding = r.TransferEncoding
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receivedBody = body
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL, WithStreamingRequests())
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var result string
+	if err := client.Call("upload", payload, &result); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+
+	want, err := EncodeMethodCall("upload", payload)
+	if err != nil {
+		t.Fatalf("EncodeMethodCall error: %v", err)
+	}
+	if string(receivedBody) != string(want) {
+		t.Fatalf("body mismatch: expected %d bytes, got %d", len(want), len(receivedBody))
+	}
+	if len(receivedEncoding) != 1 || receivedEncoding[0] != "chunked" {
+		t.Errorf("expected chunked transfer encoding, got %v", receivedEncoding)
+	}
+}
+
+func TestCallWithStreamingRequestsEncodeError(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	})
+
+	client, err := NewClientWithOptions(ts.URL, WithStreamingRequests())
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var result string
+	err = client.Call("test.method", make(chan int), &result)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "unsupported type") {
+		t.Fatalf("expected encoding error, got: %v", err)
+	}
+}
+
+func TestCallCompressedResponse(t *testing.T) {
+	t.Parallel()
+
+	const response = `<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`
+
+	tests := []struct {
+		name               string
+		encoding           string
+		opts               []Option
+		wantAcceptEncoding string
+	}{
+		{"gzip", "gzip", nil, ""},
+		{"gzip_advertised", "gzip", []Option{WithAcceptEncoding("gzip", "deflate")}, "gzip, deflate"},
+		{"deflate", "deflate", []Option{WithAcceptEncoding("deflate")}, "deflate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var receivedAcceptEncoding string
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				receivedAcceptEncoding = r.Header.Get("Accept-Encoding")
+
+				var zw io.WriteCloser
+				if tt.encoding == "gzip" {
+					zw = gzip.NewWriter(w)
+				} else {
+					zw = zlib.NewWriter(w)
+				}
+				w.Header().Set("Content-Encoding", tt.encoding)
+				if _, err := io.WriteString(zw, response); err != nil {
+					t.Fatal(err)
+				}
+				if err := zw.Close(); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			if err := client.Call("test.method", nil, &result); err != nil {
+				t.Fatalf("Call error: %v", err)
+			}
+			if result != "ok" {
+				t.Fatalf("expected 'ok', got %q", result)
+			}
+			if tt.wantAcceptEncoding != "" && receivedAcceptEncoding != tt.wantAcceptEncoding {
+				t.Errorf(
+					"Accept-Encoding: expected %q, got %q",
+					tt.wantAcceptEncoding,
+					receivedAcceptEncoding,
+				)
+			}
+		})
+	}
+}
+
+func TestCallWithRequestCompression(t *testing.T) {
+	t.Parallel()
+
+	args := []any{strings.Repeat("compressible ", 200)}
+	want, err := EncodeMethodCall("test.method", args...)
+	if err != nil {
+		t.Fatalf("EncodeMethodCall error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		wantCompressed bool
+	}{
+		{"gzip", []Option{WithRequestCompression("gzip")}, true},
+		{
+			"below_threshold",
+			[]Option{WithRequestCompression("gzip"), WithRequestCompressionThreshold(len(want) + 1)},
+			false,
+		},
+		{
+			"above_threshold",
+			[]Option{WithRequestCompression("gzip"), WithRequestCompressionThreshold(len(want))},
+			true,
+		},
+		{"streaming", []Option{WithRequestCompression("gzip"), WithStreamingRequests()}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				receivedEncoding string
+				receivedBody     []byte
+			)
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				receivedEncoding = r.Header.Get("Content-Encoding")
+
+				var body io.Reader = r.Body
+				if receivedEncoding == "gzip" {
+					zr, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Fatal(err)
+					}
+					body = zr
+				}
+				b, err := io.ReadAll(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				receivedBody = b
+
+				if _, err := io.WriteString(
+					w,
+					`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+				); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			if err := client.Call("test.method", args, &result); err != nil {
+				t.Fatalf("Call error: %v", err)
+			}
+
+			if compressed := receivedEncoding == "gzip"; compressed != tt.wantCompressed {
+				t.Errorf("expected compressed=%t, got Content-Encoding %q", tt.wantCompressed, receivedEncoding)
+			}
+			if string(receivedBody) != string(want) {
+				t.Errorf("body mismatch:\nexpected: %s\n     got: %s", want, receivedBody)
+			}
+		})
+	}
+}
+
+func TestRequestCompressionUnsupported(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewClientWithOptions("http://example.com", WithRequestCompression("br")); err == nil {
+		t.Fatal("expected error for unsupported compression, got nil")
+	}
+}
+
+func TestCallWithMaxResponseSize(t *testing.T) {
+	t.Parallel()
+
+	const small = `<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var out io.Writer = w
+		if r.URL.Query().Has("gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			defer zw.Close()
+			out = zw
+		}
+		if r.URL.Query().Has("small") {
+			if _, err := io.WriteString(out, small); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+
+		// Stream a large array in chunks.
+		if _, err := io.WriteString(out, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>`); err != nil {
+			return
+		}
+		for range 10000 {
+			if _, err := io.WriteString(out, `<value><string>0123456789abcdef</string></value>`); err != nil {
+				return
+			}
+		}
+		_, _ = io.WriteString(out, `</data></array></value></param></params></methodResponse>`)
+	})
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"small", "?small", false},
+		{"large", "", true},
+		{"large_gzip", "?gzip", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClientWithOptions(ts.URL+tt.query, WithMaxResponseSize(int64(len(small))))
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result any
+			err = client.Call("test.method", nil, &result)
+			if tt.wantErr {
+				if !errors.Is(err, ErrResponseTooLarge) {
+					t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Call error: %v", err)
+			}
+			if result != "ok" {
+				t.Fatalf("expected %q, got %v", "ok", result)
+			}
+		})
+	}
+}
+
+func TestCallWithRequestSigner(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	sign := func(method string, body []byte) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(method))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var attempts atomic.Int32
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.Header.Get("X-Signature"), sign("test.method", body); got != want {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		// Fail the first attempt to check the signer runs per attempt.
+		if attempts.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var signed atomic.Int32
+	signer := func(ctx context.Context, method string, body []byte) (http.Header, error) {
+		signed.Add(1)
+		return http.Header{"X-Signature": {sign(method, body)}}, nil
+	}
+
+	client, err := NewClientWithOptions(ts.URL,
+		WithRequestSigner(signer),
+		WithRequestCompression("gzip"),
+		WithRetry(2, nil),
+	)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var result string
+	if err := client.Call("test.method", []any{"hello", 42}, &result); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if got := signed.Load(); got != 2 {
+		t.Fatalf("expected signer to run for each of 2 attempts, ran %d times", got)
+	}
+}
+
+func TestCallWithRequestSignerError(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClientWithOptions("http://example.com",
+		WithRequestSigner(func(context.Context, string, []byte) (http.Header, error) {
+			return nil, errors.New("no key")
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var result string
+	if err := client.Call("test.method", nil, &result); err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Fatalf("expected signer error, got: %v", err)
+	}
+}
+
+func TestCallBadStatus(t *testing.T) {
+	t.Parallel()
+
+	statusCodes := []int{400, 401, 403, 404, 500, 502, 503}
+
+	for _, code := range statusCodes {
+		t.Run(http.StatusText(code), func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "error", code)
+			})
+
+			client, err := NewClientWithOptions(ts.URL)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			err = client.Call("test.method", nil, &result)
+
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("expected *HTTPError, got %T: %v", err, err)
+			}
+			if httpErr.StatusCode != code {
+				t.Errorf("expected status code %d, got %d", code, httpErr.StatusCode)
+			}
+			if want := fmt.Sprintf("%d %s", code, http.StatusText(code)); httpErr.Status != want {
+				t.Errorf("expected status %q, got %q", want, httpErr.Status)
+			}
+			if got := strings.TrimSpace(string(httpErr.Body)); got != "error" {
+				t.Errorf("expected body %q, got %q", "error", got)
+			}
+		})
+	}
+}
+
+func TestCallFull(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(body), "rate.limited") {
+			w.Header().Set("Retry-After", "120")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("X-Request-Id", "abc-123")
+		if _, err := io.WriteString(
+			w,
+			`<methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		var result string
+		resp, err := client.CallFull(context.Background(), "test.method", nil, &result)
+		if err != nil {
+			t.Fatalf("CallFull error: %v", err)
+		}
+		if result != "ok" {
+			t.Errorf("expected result %q, got %q", "ok", result)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status 200, got %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Request-Id"); got != "abc-123" {
+			t.Errorf("expected X-Request-Id header, got %q", got)
+		}
+		if !strings.Contains(string(resp.Raw), "<string>ok</string>") {
+			t.Errorf("expected raw body, got %q", resp.Raw)
+		}
+	})
+
+	t.Run("too_many_requests", func(t *testing.T) {
+		t.Parallel()
+
+		var result string
+		resp, err := client.CallFull(context.Background(), "rate.limited", nil, &result)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if resp == nil {
+			t.Fatal("expected response, got nil")
+		}
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("expected status 429, got %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Retry-After"); got != "120" {
+			t.Errorf("expected Retry-After header 120, got %q", got)
+		}
+		if !strings.Contains(string(resp.Raw), "slow down") {
+			t.Errorf("expected raw body, got %q", resp.Raw)
+		}
+	})
+
+	t.Run("no_response", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		resp, err := client.CallFull(ctx, "test.method", nil, nil)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if resp != nil {
+			t.Fatalf("expected nil response, got %+v", resp)
+		}
+	})
+}
+
+func TestCallConnectionResetAfterBody(t *testing.T) {
+	t.Parallel()
+
+	const body = `<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// Announce more bytes than are sent, then reset the connection.
+		send := body
+		if r.URL.Query().Has("truncated") {
+			send = body[:len(body)-len("</params></methodResponse>")]
+		}
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: text/xml\r\nContent-Length: %d\r\n\r\n%s", len(body)+100, send)
+		if err := buf.Flush(); err != nil {
+			t.Error(err)
+			return
+		}
+		if tc, ok := conn.(*net.TCPConn); ok {
+			_ = tc.SetLinger(0)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"complete", "", false},
+		{"truncated", "?truncated", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClientWithOptions(ts.URL + tt.query)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			_, err = client.CallFull(context.Background(), "test.method", nil, &result)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for truncated body, got result %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CallFull error: %v", err)
+			}
+			if result != "ok" {
+				t.Fatalf("expected %q, got %q", "ok", result)
+			}
+
+			result = ""
+			if err := client.Call("test.method", nil, &result); err != nil {
+				t.Fatalf("Call error: %v", err)
+			}
+			if result != "ok" {
+				t.Fatalf("expected %q, got %q", "ok", result)
+			}
+		})
+	}
+}
+
+func TestCallFaultResponse(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+			<methodResponse>
+				<fault>
+					<value>
+						<struct>
+							<member>
+								<name>faultCode</name>
+								<value><int>4</int></value>
+							</member>
+							<member>
+								<name>faultString</name>
+								<value><string>Too many parameters.</string></value>
+							</member>
+						</struct>
+					</value>
+				</fault>
+			</methodResponse>`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var result string
+	err = client.Call("test.method", nil, &result)
+	if err == nil {
+		t.Fatal("expected fault error, got nil")
+	}
+
+	fault, ok := err.(FaultError)
+	if !ok {
+		t.Fatalf("expected FaultError, got %T: %v", err, err)
+	}
+	if fault.Code != 4 {
+		t.Errorf("expected fault code 4, got %d", fault.Code)
+	}
+	if !strings.Contains(fault.String, "Too many parameters") {
+		t.Errorf("expected 'Too many parameters' in fault string, got %q", fault.String)
+	}
+}
+
+func TestCallChan(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp string
+		switch {
+		case strings.Contains(string(body), "stream.broken"):
+			resp = `<methodResponse><params><param><value><array><data>` +
+				`<value><int>1</int></value><value><int>2</int></value><value><string>three</string></value><value><int>4</int></value>` +
+				`</data></array></value></param></params></methodResponse>`
+		case strings.Contains(string(body), "stream.fault"):
+			resp = `<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value><int>4</int></value></member>` +
+				`<member><name>faultString</name><value><string>Too many parameters.</string></value></member>` +
+				`</struct></value></fault></methodResponse>`
+		case strings.Contains(string(body), "stream.scalar"):
+			resp = `<methodResponse><params><param><value><int>1</int></value></param></params></methodResponse>`
+		default:
+			resp = `<methodResponse><params><param><value><array><data>` +
+				`<value><int>1</int></value> <value><int>2</int></value> <value><int>3</int></value>` +
+				`</data></array></value></param></params></methodResponse>`
+		}
+		if _, err := io.WriteString(w, resp); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		want      []int
+		wantErr   bool
+		wantFault bool
+	}{
+		{"success", "stream.ok", []int{1, 2, 3}, false, false},
+		{"decode_error", "stream.broken", []int{1, 2}, true, false},
+		{"fault", "stream.fault", nil, true, true},
+		{"not_array", "stream.scalar", nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			out := make(chan int)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- CallChan(context.Background(), client, tt.method, nil, out)
+			}()
+
+			var got []int
+			for v := range out {
+				got = append(got, v)
+			}
+			err := <-errc
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected elements %v, got %v", tt.want, got)
+			}
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			var fault FaultError
+			if tt.wantFault != errors.As(err, &fault) {
+				t.Fatalf("expected fault %v, got %T: %v", tt.wantFault, err, err)
+			}
+		})
+	}
+}
+
+func TestCallStream(t *testing.T) {
+	t.Parallel()
+
+	const n = 1000
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		b.WriteString(`<methodResponse><params><param><value><array><data>`)
+		for i := range n {
+			fmt.Fprintf(&b, `<value><struct>`+
+				`<member><name>id</name><value><int>%d</int></value></member>`+
+				`<member><name>name</name><value><string>item %d</string></value></member>`+
+				`</struct></value>`, i, i)
+		}
+		b.WriteString(`</data></array></value></param></params></methodResponse>`)
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	type item struct {
+		ID   int    `xmlrpc:"id"`
+		Name string `xmlrpc:"name"`
+	}
+
+	t.Run("decode", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		err := client.CallStream(t.Context(), "items.list", nil, func(decode func(v any) error) error {
+			var it item
+			if err := decode(&it); err != nil {
+				return err
+			}
+			if want := (item{ID: calls, Name: fmt.Sprintf("item %d", calls)}); it != want {
+				t.Errorf("element %d: expected %+v, got %+v", calls, want, it)
+			}
+			calls++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("CallStream error: %v", err)
+		}
+		if calls != n {
+			t.Errorf("expected %d callbacks, got %d", n, calls)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		err := client.CallStream(t.Context(), "items.list", nil, func(decode func(v any) error) error {
+			calls++
+			if calls%2 == 1 {
+				return nil
+			}
+			var it item
+			return decode(&it)
+		})
+		if err != nil {
+			t.Fatalf("CallStream error: %v", err)
+		}
+		if calls != n {
+			t.Errorf("expected %d callbacks, got %d", n, calls)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		t.Parallel()
+
+		errStop := errors.New("stop")
+		calls := 0
+		err := client.CallStream(t.Context(), "items.list", nil, func(decode func(v any) error) error {
+			calls++
+			if calls == 10 {
+				return errStop
+			}
+			return nil
+		})
+		if !errors.Is(err, errStop) {
+			t.Fatalf("expected stop error, got %v", err)
+		}
+		if calls != 10 {
+			t.Errorf("expected 10 callbacks, got %d", calls)
+		}
+	})
+}
+
+func TestCallWithResult(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp string
+		switch {
+		case strings.Contains(string(body), "fail.transport"):
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		case strings.Contains(string(body), "fail.fault"):
+			resp = `<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value><int>4</int></value></member>` +
+				`<member><name>faultString</name><value><string>Too many parameters.</string></value></member>` +
+				`</struct></value></fault></methodResponse>`
+		default:
+			resp = `<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>`
+		}
+		if _, err := io.WriteString(w, resp); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	t.Run("value", func(t *testing.T) {
+		t.Parallel()
+
+		value, fault, err := client.CallWithResult(ctx, "ok", nil, nil)
+		if err != nil || fault != nil {
+			t.Fatalf("unexpected fault %v or error %v", fault, err)
+		}
+		if value != int64(42) {
+			t.Fatalf("expected int64(42), got %#v", value)
+		}
+
+		var n int
+		value, fault, err = client.CallWithResult(ctx, "ok", nil, &n)
+		if err != nil || fault != nil {
+			t.Fatalf("unexpected fault %v or error %v", fault, err)
+		}
+		if value != &n || n != 42 {
+			t.Fatalf("expected reply pointer holding 42, got %#v (n=%d)", value, n)
+		}
+	})
+
+	t.Run("fault", func(t *testing.T) {
+		t.Parallel()
+
+		value, fault, err := client.CallWithResult(ctx, "fail.fault", nil, nil)
+		if err != nil || value != nil {
+			t.Fatalf("unexpected value %v or error %v", value, err)
+		}
+		if fault == nil || fault.Code != 4 {
+			t.Fatalf("expected fault code 4, got %v", fault)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		value, fault, err := client.CallWithResult(ctx, "fail.transport", nil, nil)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if value != nil || fault != nil {
+			t.Fatalf("unexpected value %v or fault %v", value, fault)
+		}
+	})
+}
+
+func TestCallRaw(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp string
+		switch {
+		case strings.Contains(string(body), "fail.transport"):
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		case strings.Contains(string(body), "fail.fault"):
+			resp = `<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value><int>4</int></value></member>` +
+				`<member><name>faultString</name><value><string>Partial failure</string></value></member>` +
+				`<member><name>done</name><value><array><data><value><int>1</int></value></data></array></value></member>` +
+				`</struct></value></fault></methodResponse>`
+		default:
+			resp = `<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>`
+		}
+		if _, err := io.WriteString(w, resp); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := client.CallRaw(ctx, "ok", nil)
+		if err != nil {
+			t.Fatalf("CallRaw error: %v", err)
+		}
+		if fault := result.Fault(); fault != nil {
+			t.Fatalf("unexpected fault %v", fault)
+		}
+		var n int
+		if err := result.Decode(&n); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		if n != 42 {
+			t.Errorf("expected 42, got %d", n)
+		}
+	})
+
+	t.Run("fault", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := client.CallRaw(ctx, "fail.fault", nil)
+		if err != nil {
+			t.Fatalf("CallRaw error: %v", err)
+		}
+		fault := result.Fault()
+		if fault == nil || fault.Code != 4 || fault.String != "Partial failure" {
+			t.Fatalf("expected fault code 4, got %v", fault)
+		}
+		var data struct {
+			FaultCode int   `xmlrpc:"faultCode"`
+			Done      []int `xmlrpc:"done"`
+		}
+		if err := result.Decode(&data); err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		if data.FaultCode != 4 || !reflect.DeepEqual(data.Done, []int{1}) {
+			t.Errorf("unexpected fault data %+v", data)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := client.CallRaw(ctx, "fail.transport", nil)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if result != nil {
+			t.Fatalf("unexpected result %v", result)
+		}
+	})
+}
+
+func TestCallRichFaultResponse(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+			<methodResponse>
+				<fault>
+					<value>
+						<struct>
+							<member>
+								<name>faultCode</name>
+								<value><int>4</int></value>
+							</member>
+							<member>
+								<name>faultString</name>
+								<value><string>Too many parameters.</string></value>
+							</member>
+							<member>
+								<name>traceId</name>
+								<value><string>abc-123</string></value>
+							</member>
+						</struct>
+					</value>
+				</fault>
+			</methodResponse>`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantRaw bool
+	}{
+		{"default", nil, false},
+		{"rich", []Option{WithRichFaults()}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			err = client.Call("test.method", nil, &result)
+
+			var fault FaultError
+			if !errors.As(err, &fault) {
+				t.Fatalf("expected FaultError, got %T: %v", err, err)
+			}
+			if fault.Code != 4 || fault.String != "Too many parameters." {
+				t.Errorf("unexpected fault: %+v", fault)
+			}
+			if !tt.wantRaw {
+				if raw := fault.RawMembers(); raw != nil {
+					t.Errorf("expected nil RawMembers, got %v", raw)
+				}
+				return
+			}
+			if got := fault.RawMembers()["traceId"]; got != "abc-123" {
+				t.Errorf("expected traceId 'abc-123' in RawMembers, got %v", got)
+			}
+		})
+	}
+}
+
+func TestCallErrorStages(t *testing.T) {
+	t.Parallel()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	garbage := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "this is not XML")
+	})
+	stages := []error{ErrEncode, ErrTransport, ErrDecode}
+
+	tests := []struct {
+		name string
+		url  string
+		args any
+		opts []Option
+		want error
+	}{
+		{"transport", closed.URL, nil, nil, ErrTransport},
+		{"decode", garbage.URL, nil, nil, ErrDecode},
+		{"encode", garbage.URL, []any{make(chan int)}, nil, ErrEncode},
+		{"encode_streaming", garbage.URL, []any{make(chan int)}, []Option{WithStreamingRequests()}, ErrEncode},
+		{
+			"signer", garbage.URL, nil,
+			[]Option{WithRequestSigner(func(context.Context, string, []byte) (http.Header, error) {
+				return nil, errors.New("no key")
+			})},
+			ErrEncode,
+		},
+		{"required_header", garbage.URL, nil, []Option{WithRequiredResponseHeaders("X-Request-Id")}, ErrDecode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClientWithOptions(tt.url, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result any
+			err = client.CallContext(context.Background(), "test.method", tt.args, &result)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			for _, stage := range stages {
+				if got := errors.Is(err, stage); got != (stage == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, stage, got)
+				}
+			}
+		})
+	}
+
+	client, err := NewClientWithOptions(closed.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	err = client.Call("test.method", nil, nil)
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Errorf("errors.As(%v, *url.Error) failed", err)
+	}
+}
+
+func TestCallBadStatusRecovery(t *testing.T) {
+	t.Parallel()
+
+	callCount := 0
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		if callCount == 1 {
+			http.Error(w, "bad status", http.StatusInternalServerError)
+			return
+		}
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param><value><struct></struct></value></param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClient(ts.URL, nil)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	defer client.Close()
+
+	var result any
+
+	// First call should fail
+	if err := client.Call("method", nil, &result); err == nil {
+		t.Fatal("expected error on first call")
+	}
+
+	// Second call should succeed
+	if err := client.Call("method", nil, &result); err != nil {
+		t.Fatalf("expected success on second call, got: %v", err)
+	}
+}
+
+func TestCallWithRetry(t *testing.T) {
+	t.Parallel()
+
+	const faultResponse = `<?xml version="1.0"?><methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>fault</string></value></member></struct></value></fault></methodResponse>`
+
+	tests := []struct {
+		name         string
+		failures     int
+		failStatus   int
+		opts         []Option
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{"recovers", 2, http.StatusServiceUnavailable, []Option{WithRetry(3, nil)}, false, 3},
+		{"exhausted", 5, http.StatusBadGateway, []Option{WithRetry(3, nil)}, true, 3},
+		{"no_retry_by_default", 1, http.StatusServiceUnavailable, nil, true, 1},
+		{"status_not_retried", 1, http.StatusBadRequest, []Option{WithRetry(3, nil)}, true, 1},
+		{
+			"custom_status",
+			1,
+			http.StatusInternalServerError,
+			[]Option{WithRetry(3, nil), WithRetryStatusCodes(http.StatusInternalServerError)},
+			false,
+			2,
+		},
+		{"fault_not_retried", 1, 0, []Option{WithRetry(3, nil)}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts atomic.Int32
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				if int(n) <= tt.failures {
+					if tt.failStatus == 0 {
+						_, _ = io.WriteString(w, faultResponse)
+						return
+					}
+					http.Error(w, "unavailable", tt.failStatus)
+					return
+				}
+				if _, err := io.WriteString(
+					w,
+					`<?xml version="1.0"?><methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+				); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			var result string
+			err = client.Call("test.method", nil, &result)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Fatalf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func TestCallWithRetryBackoff(t *testing.T) {
+	t.Parallel()
+
+	var attempts atomic.Int32
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	var backoffs []int
+	client, err := NewClientWithOptions(ts.URL, WithRetry(3, func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		return time.Millisecond
+	}))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var result string
+	if err := client.Call("test.method", nil, &result); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(backoffs) != 2 || backoffs[0] != 1 || backoffs[1] != 2 {
+		t.Fatalf("expected backoff for attempts [1 2], got %v", backoffs)
+	}
+}
+
+func TestCallWithRetryContextDeadline(t *testing.T) {
+	t.Parallel()
+
+	var attempts atomic.Int32
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	client, err := NewClientWithOptions(ts.URL, WithRetry(5, func(int) time.Duration {
+		return time.Hour
+	}))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	var result string
+	err = client.CallContext(ctx, "test.method", nil, &result)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestCallWithHTTPClientTimeout(t *testing.T) {
+	t.Parallel()
+
+	done := make(chan struct{})
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+	// Cleanups run in reverse order, so the handler returns before the
+	// server is closed.
+	t.Cleanup(func() { close(done) })
+
+	tests := []struct {
+		name          string
+		ctxTimeout    time.Duration
+		clientTimeout time.Duration
+	}{
+		{"client_timeout_first", time.Hour, 50 * time.Millisecond},
+		{"context_deadline_first", 50 * time.Millisecond, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClientWithOptions(ts.URL, WithHTTPClientTimeout(tt.clientTimeout))
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(t.Context(), tt.ctxTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err = client.CallContext(ctx, "test.method", nil, nil)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("call took %v, expected the earlier deadline to cancel it", elapsed)
+			}
+		})
 	}
 }
 
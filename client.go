@@ -2,6 +2,7 @@
 //
 // The package implements the client side of the XML-RPC protocol,
 // allowing Go programs to make remote procedure calls to XML-RPC servers.
+// A minimal [Handler] serves methods for the server side, e.g. in tests.
 //
 // Basic usage:
 //
@@ -19,19 +20,92 @@
 package xmlrpc
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Config holds process-wide defaults applied to every client created by
+// [NewClientWithOptions]. See [SetDefaults].
+//
+// Every option passed to NewClientWithOptions takes precedence over the
+// matching default, e.g. [WithTimeout] over Timeout and a User-Agent header
+// added with [WithHeader] over UserAgent. Like WithTimeout, the default
+// Timeout is ignored if [WithHTTPClient] is used.
+type Config struct {
+	// Timeout is the default for [WithTimeout].
+	Timeout time.Duration
+	// UserAgent is the default User-Agent header, sent unless a User-Agent
+	// header is added with [WithHeader].
+	UserAgent string
+	// MaxResponseSize is the default for [WithMaxResponseSize].
+	MaxResponseSize int64
+}
+
+var (
+	defaultsMu sync.RWMutex
+	defaults   Config
+)
+
+// SetDefaults sets the process-wide defaults for new clients.
+// The defaults are applied before the options passed to [NewClientWithOptions],
+// so every per-client option takes precedence over its default. Clients created
+// before the call are not affected. SetDefaults is safe for concurrent use.
+func SetDefaults(cfg Config) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaults = cfg
+}
+
 // clientOptions holds configuration for the XML-RPC client.
 type clientOptions struct {
 	httpClient *http.Client
 	transport  http.RoundTripper
+	timeout    time.Duration
+	// callTimeout bounds every call in addition to its context
+	callTimeout time.Duration
+	// tcpKeepAlive configures the dialer of the package-built transport
+	tcpKeepAlive time.Duration
+	// disableKeepAlives disables connection reuse of the package-built transport
+	disableKeepAlives bool
+	// tlsConfig configures TLS of the package-built transport
+	tlsConfig *tls.Config
+	// unixSocket is the socket path the package-built transport dials
+	unixSocket string
 	headers    http.Header
+	accept     string
+	userAgent  string
 	cookieJar  http.CookieJar
 	// useCookies distinguishes between "no jar set" and "explicitly disabled"
 	useCookies *bool
+	// seedCookies are stored in the jar when the client is created
+	seedCookies    []cookieSeed
+	encode         encodeOptions
+	decode         decodeOptions
+	compress       compressOptions
+	acceptEncoding string
+	acceptLanguage string
+	// requiredResponseHeaders must be present in every response
+	requiredResponseHeaders []string
+	streamRequests          bool
+	retryAttempts           int
+	retryBackoff            func(attempt int) time.Duration
+	retryStatusCodes        []int
+	signer                  RequestSigner
+	maxResponseSize         int64
+	logger                  func(CallInfo)
+	requestHook             func(ctx context.Context, req *http.Request)
+	responseHook            func(ctx context.Context, resp *http.Response, err error)
+	batchConcurrency        int
+	pingMethod              string
 }
 
 // Option configures a [Client].
@@ -53,6 +127,73 @@ func WithTransport(transport http.RoundTripper) Option {
 	}
 }
 
+// WithTimeout sets the timeout of the HTTP client used for requests.
+// The timeout includes connection time, redirects and reading the response body.
+// Ignored if [WithHTTPClient] is also used; set the timeout on that client instead.
+func WithTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = d
+	}
+}
+
+// WithHTTPClientTimeout sets a hard ceiling on the duration of every call,
+// including retries and decoding the response, regardless of the context
+// passed by the caller. If the context has a deadline as well, the earlier of
+// the two wins. Unlike [WithTimeout], it also applies with [WithHTTPClient].
+func WithHTTPClientTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.callTimeout = d
+	}
+}
+
+// WithTCPKeepAlive enables TCP keep-alive probes on idle connections, sent
+// after the connection has been idle for d and then every d, so that
+// connections dropped by NATs or firewalls are detected before a call uses
+// them. A negative duration disables keep-alive probes. Ignored if
+// [WithHTTPClient] or [WithTransport] is also used.
+func WithTCPKeepAlive(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.tcpKeepAlive = d
+	}
+}
+
+// WithDisableKeepAlives makes the client open a new connection for every
+// request and close it afterwards, e.g. so that calls are spread over the
+// backends behind a load balancer instead of sticking to one. It sets
+// DisableKeepAlives on the transport created for the client, a clone of
+// [http.DefaultTransport]. Ignored if [WithHTTPClient] or [WithTransport] is
+// also used; set DisableKeepAlives on that transport instead.
+func WithDisableKeepAlives() Option {
+	return func(o *clientOptions) {
+		o.disableKeepAlives = true
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the transport created for the
+// client, e.g. to trust a private CA or present client certificates. The
+// transport is a clone of [http.DefaultTransport]. It cannot be combined with
+// [WithHTTPClient] or [WithTransport]; configure TLS on those instead.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *clientOptions) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithUnixSocket makes the client connect to the Unix domain socket at path
+// instead of the host of the URL, e.g. for supervisord:
+//
+//	client, err := xmlrpc.NewClientWithOptions("http://localhost/RPC2",
+//		xmlrpc.WithUnixSocket("/var/run/supervisor.sock"),
+//	)
+//
+// Requests are still sent to the path of the URL. It cannot be combined with
+// [WithHTTPClient] or [WithTransport].
+func WithUnixSocket(path string) Option {
+	return func(o *clientOptions) {
+		o.unixSocket = path
+	}
+}
+
 // WithHeader adds a header to all requests.
 // Can be called multiple times to add multiple headers.
 func WithHeader(key, value string) Option {
@@ -64,14 +205,209 @@ func WithHeader(key, value string) Option {
 	}
 }
 
+// WithAccept sets the Accept header sent with all requests. Takes precedence
+// over an Accept header added with [WithHeader]. If neither is used, the
+// Accept header defaults to "text/xml".
+func WithAccept(mime string) Option {
+	return func(o *clientOptions) {
+		o.accept = mime
+	}
+}
+
+// WithAcceptEncoding sets the Accept-Encoding header sent with all requests,
+// e.g. WithAcceptEncoding("gzip", "deflate"). Responses compressed with gzip or
+// deflate are decompressed regardless of this option.
+//
+// By default the HTTP transport requests gzip compression itself and
+// decompresses responses transparently; setting this option disables that
+// behavior of the transport.
+func WithAcceptEncoding(encodings ...string) Option {
+	return func(o *clientOptions) {
+		o.acceptEncoding = strings.Join(encodings, ", ")
+	}
+}
+
+// WithAcceptLanguage sets the Accept-Language header sent with all requests,
+// e.g. to receive fault strings in a given language. Tags are listed in order
+// of preference and get descending quality values starting at 1.0, so
+// WithAcceptLanguage("en", "de") sends "en;q=1.0, de;q=0.9". A tag that
+// already has a quality value, like "de;q=0.8", is sent unchanged.
+func WithAcceptLanguage(tags ...string) Option {
+	return func(o *clientOptions) {
+		values := make([]string, len(tags))
+		for i, tag := range tags {
+			if strings.Contains(tag, ";") {
+				values[i] = tag
+				continue
+			}
+			q := max(10-i, 1)
+			values[i] = fmt.Sprintf("%s;q=%d.%d", tag, q/10, q%10)
+		}
+		o.acceptLanguage = strings.Join(values, ", ")
+	}
+}
+
 // WithBasicAuth sets basic authentication for all requests.
+//
+// WithBasicAuth, [WithBearerToken] and [WithAuthorization] all set the
+// Authorization header, replacing any previous value; the last of them
+// passed to [NewClientWithOptions] wins.
 func WithBasicAuth(username, password string) Option {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return WithAuthorization("Basic " + credentials)
+}
+
+// WithBearerToken sets the Authorization header of all requests to
+// "Bearer " followed by token. See [WithBasicAuth] for how it interacts
+// with the other authorization options.
+func WithBearerToken(token string) Option {
+	return WithAuthorization("Bearer " + token)
+}
+
+// WithAuthorization sets the Authorization header of all requests to value.
+// See [WithBasicAuth] for how it interacts with the other authorization options.
+func WithAuthorization(value string) Option {
 	return func(o *clientOptions) {
 		if o.headers == nil {
 			o.headers = make(http.Header)
 		}
-		req := &http.Request{Header: o.headers}
-		req.SetBasicAuth(username, password)
+		o.headers.Set("Authorization", value)
+	}
+}
+
+// WithRequiredResponseHeaders makes calls fail if any of the given headers
+// is missing from the response. A header sent with an empty value counts as
+// present. The check runs before the body is decoded.
+// Can be called multiple times to require additional headers.
+func WithRequiredResponseHeaders(keys ...string) Option {
+	return func(o *clientOptions) {
+		o.requiredResponseHeaders = append(o.requiredResponseHeaders, keys...)
+	}
+}
+
+// WithStreamingRequests makes the client encode request bodies while they are
+// being sent instead of buffering them first, reducing peak memory for large
+// calls. Streamed requests have no Content-Length and use chunked transfer
+// encoding, which some servers do not support. Encoding errors are reported
+// by the call once the body is written.
+func WithStreamingRequests() Option {
+	return func(o *clientOptions) {
+		o.streamRequests = true
+	}
+}
+
+// WithRetry makes the client retry failed calls up to maxAttempts attempts in
+// total. Calls are retried on network errors and on the status codes set with
+// [WithRetryStatusCodes], which default to 502, 503 and 504. A [FaultError] or
+// any other error after a response has been received is never retried.
+//
+// Before each retry the client waits for backoff(attempt), where attempt is
+// the number of the attempt that failed, starting at 1. A nil backoff retries
+// immediately. Waiting stops early when the context passed to
+// [Client.CallContext] is done.
+//
+// Only use retries for methods that are safe to call more than once.
+func WithRetry(maxAttempts int, backoff func(attempt int) time.Duration) Option {
+	return func(o *clientOptions) {
+		o.retryAttempts = maxAttempts
+		o.retryBackoff = backoff
+	}
+}
+
+// WithRetryStatusCodes sets the HTTP status codes that are retried when
+// [WithRetry] is used, replacing the defaults.
+func WithRetryStatusCodes(codes ...int) Option {
+	return func(o *clientOptions) {
+		o.retryStatusCodes = codes
+	}
+}
+
+// WithRequestCompression compresses request bodies with the given content
+// encoding and sets the Content-Encoding header. Only "gzip" is supported;
+// [NewClientWithOptions] returns an error for any other encoding.
+// Use [WithRequestCompressionThreshold] to only compress large bodies.
+func WithRequestCompression(enc string) Option {
+	return func(o *clientOptions) {
+		o.compress.encoding = enc
+	}
+}
+
+// WithRequestCompressionThreshold sets the minimum encoded body size in bytes
+// for [WithRequestCompression] to apply. Smaller bodies are sent uncompressed.
+// Streamed requests are always compressed, see [WithStreamingRequests].
+func WithRequestCompressionThreshold(n int) Option {
+	return func(o *clientOptions) {
+		o.compress.threshold = n
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies to n bytes, after
+// removing any content encoding. Calls whose response exceeds the limit fail
+// with [ErrResponseTooLarge]. A limit of 0 or less means no limit, which is
+// the default.
+func WithMaxResponseSize(n int64) Option {
+	return func(o *clientOptions) {
+		o.maxResponseSize = n
+	}
+}
+
+// WithLogger sets a function called after every call with a [CallInfo]
+// describing it, e.g. to log calls or record metrics. With [WithRetry] it is
+// called once per call, with the details of the last attempt. The function is
+// called synchronously and must be safe for concurrent use if the client is.
+func WithLogger(fn func(info CallInfo)) Option {
+	return func(o *clientOptions) {
+		o.logger = fn
+	}
+}
+
+// WithRequestHook sets a function called with every HTTP request right before
+// it is sent, after all headers and cookies have been applied, e.g. to start a
+// tracing span and inject trace headers. It is called once per attempt.
+func WithRequestHook(fn func(ctx context.Context, req *http.Request)) Option {
+	return func(o *clientOptions) {
+		o.requestHook = fn
+	}
+}
+
+// WithResponseHook sets a function called after every HTTP request with its
+// response or transport error, e.g. to end a tracing span. resp is nil if err
+// is non-nil. The hook must not read or close the response body. It is called
+// once per attempt.
+func WithResponseHook(fn func(ctx context.Context, resp *http.Response, err error)) Option {
+	return func(o *clientOptions) {
+		o.responseHook = fn
+	}
+}
+
+// RequestSigner computes signature headers for a request. It receives the
+// XML-RPC method name and the request body exactly as it is sent, after any
+// compression. The returned headers are set on the request, replacing
+// existing values.
+type RequestSigner func(ctx context.Context, method string, body []byte) (http.Header, error)
+
+// WithRequestSigner sets a signer that is called before every request,
+// including each retry attempt. It cannot be combined with
+// [WithStreamingRequests], since streamed bodies are not known in advance.
+func WithRequestSigner(signer RequestSigner) Option {
+	return func(o *clientOptions) {
+		o.signer = signer
+	}
+}
+
+// WithBatchConcurrency sets the maximum number of calls of a
+// [Client.CallBatch] batch that run at the same time. Defaults to 4.
+func WithBatchConcurrency(n int) Option {
+	return func(o *clientOptions) {
+		o.batchConcurrency = n
+	}
+}
+
+// WithPingMethod sets the method called by [Client.Ping]. Defaults to
+// "system.listMethods".
+func WithPingMethod(method string) Option {
+	return func(o *clientOptions) {
+		o.pingMethod = method
 	}
 }
 
@@ -85,12 +421,51 @@ func WithCookieJar(jar http.CookieJar) Option {
 	}
 }
 
+// cookieSeed holds cookies set with [WithCookies] for a URL, or for the
+// client URL if url is nil.
+type cookieSeed struct {
+	url     *url.URL
+	cookies []*http.Cookie
+}
+
+// WithCookies stores cookies for u in the cookie jar of the client when it is
+// created, e.g. a session cookie obtained out of band, so they are sent with
+// the first request. A nil u stands for the client URL. Can be used multiple
+// times. It cannot be combined with a cookie jar disabled with
+// [WithCookieJar].
+func WithCookies(u *url.URL, cookies []*http.Cookie) Option {
+	return func(o *clientOptions) {
+		o.seedCookies = append(o.seedCookies, cookieSeed{url: u, cookies: cookies})
+	}
+}
+
 // Client represents an XML-RPC client.
 type Client struct {
 	url        *url.URL
 	httpClient *http.Client
 	cookies    http.CookieJar
 	headers    http.Header
+	accept     string
+	userAgent  string
+	encode     encodeOptions
+	decode     decodeOptions
+	compress   compressOptions
+
+	acceptEncoding          string
+	acceptLanguage          string
+	requiredResponseHeaders []string
+	streamRequests          bool
+	retryAttempts           int
+	retryBackoff            func(attempt int) time.Duration
+	retryStatusCodes        []int
+	signer                  RequestSigner
+	maxResponseSize         int64
+	logger                  func(CallInfo)
+	requestHook             func(ctx context.Context, req *http.Request)
+	responseHook            func(ctx context.Context, resp *http.Response, err error)
+	batchConcurrency        int
+	callTimeout             time.Duration
+	pingMethod              string
 }
 
 // Close closes idle connections. The Client can still be used after calling Close.
@@ -101,20 +476,89 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// Cookies returns the cookies the cookie jar of the client holds for the
+// client URL, e.g. to persist a session and restore it with [WithCookies].
+// As with [http.CookieJar], only their names and values are set. It returns
+// nil if cookie handling is disabled.
+func (c *Client) Cookies() []*http.Cookie {
+	if c.cookies == nil {
+		return nil
+	}
+	return c.cookies.Cookies(c.url)
+}
+
+// newTransport returns the transport used when none is configured. Unless
+// TCP keep-alive, disabled connection reuse, a TLS configuration or a Unix
+// socket is set, this is [http.DefaultTransport].
+func newTransport(options *clientOptions) http.RoundTripper {
+	keepAlive := options.tcpKeepAlive
+	if keepAlive == 0 && !options.disableKeepAlives && options.tlsConfig == nil && options.unixSocket == "" {
+		return http.DefaultTransport
+	}
+
+	var transport *http.Transport
+	if base, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = base.Clone()
+	} else {
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+
+	if keepAlive != 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: keepAlive}
+		if keepAlive > 0 {
+			dialer.KeepAliveConfig = net.KeepAliveConfig{
+				Enable:   true,
+				Idle:     keepAlive,
+				Interval: keepAlive,
+			}
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	if options.disableKeepAlives {
+		transport.DisableKeepAlives = true
+	}
+	if options.tlsConfig != nil {
+		transport.TLSClientConfig = options.tlsConfig.Clone()
+	}
+	if path := options.unixSocket; path != "" {
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		// The socket is reached directly, never through a proxy.
+		transport.Proxy = nil
+	}
+	return transport
+}
+
 // NewClientWithOptions creates a new XML-RPC client for the given URL with the specified options.
 func NewClientWithOptions(requrl string, opts ...Option) (*Client, error) {
-	options := &clientOptions{}
+	defaultsMu.RLock()
+	options := &clientOptions{
+		timeout:         defaults.Timeout,
+		userAgent:       defaults.UserAgent,
+		maxResponseSize: defaults.MaxResponseSize,
+	}
+	defaultsMu.RUnlock()
+
 	for _, opt := range opts {
 		opt(options)
 	}
 
+	if options.tlsConfig != nil && (options.httpClient != nil || options.transport != nil) {
+		return nil, fmt.Errorf("xmlrpc: WithTLSConfig cannot be combined with WithHTTPClient or WithTransport")
+	}
+	if options.unixSocket != "" && (options.httpClient != nil || options.transport != nil) {
+		return nil, fmt.Errorf("xmlrpc: WithUnixSocket cannot be combined with WithHTTPClient or WithTransport")
+	}
+
 	httpClient := options.httpClient
 	if httpClient == nil {
 		transport := options.transport
 		if transport == nil {
-			transport = http.DefaultTransport
+			transport = newTransport(options)
 		}
-		httpClient = &http.Client{Transport: transport}
+		httpClient = &http.Client{Transport: transport, Timeout: options.timeout}
 	}
 
 	var jar http.CookieJar
@@ -130,16 +574,75 @@ func NewClientWithOptions(requrl string, opts ...Option) (*Client, error) {
 		}
 	}
 
+	if options.signer != nil && options.streamRequests {
+		return nil, fmt.Errorf("xmlrpc: request signer cannot be used with streaming requests")
+	}
+
+	if enc := options.compress.encoding; enc != "" && enc != "gzip" {
+		return nil, fmt.Errorf("xmlrpc: unsupported request compression %q", enc)
+	}
+
+	retryStatusCodes := options.retryStatusCodes
+	if retryStatusCodes == nil {
+		retryStatusCodes = []int{
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+
+	batchConcurrency := options.batchConcurrency
+	if batchConcurrency <= 0 {
+		batchConcurrency = defaultBatchConcurrency
+	}
+
+	pingMethod := options.pingMethod
+	if pingMethod == "" {
+		pingMethod = defaultPingMethod
+	}
+
 	u, err := url.Parse(requrl)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(options.seedCookies) > 0 && jar == nil {
+		return nil, fmt.Errorf("xmlrpc: WithCookies cannot be combined with a disabled cookie jar")
+	}
+	for _, seed := range options.seedCookies {
+		cu := seed.url
+		if cu == nil {
+			cu = u
+		}
+		jar.SetCookies(cu, seed.cookies)
+	}
+
 	return &Client{
 		url:        u,
 		httpClient: httpClient,
 		cookies:    jar,
 		headers:    options.headers,
+		accept:     options.accept,
+		userAgent:  options.userAgent,
+		encode:     options.encode,
+		decode:     options.decode,
+		compress:   options.compress,
+
+		acceptEncoding:          options.acceptEncoding,
+		acceptLanguage:          options.acceptLanguage,
+		requiredResponseHeaders: options.requiredResponseHeaders,
+		streamRequests:          options.streamRequests,
+		retryAttempts:           options.retryAttempts,
+		retryBackoff:            options.retryBackoff,
+		retryStatusCodes:        retryStatusCodes,
+		signer:                  options.signer,
+		maxResponseSize:         options.maxResponseSize,
+		logger:                  options.logger,
+		requestHook:             options.requestHook,
+		responseHook:            options.responseHook,
+		batchConcurrency:        batchConcurrency,
+		callTimeout:             options.callTimeout,
+		pingMethod:              pingMethod,
 	}, nil
 }
 
@@ -2,10 +2,13 @@ package xmlrpc
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
 	"slices"
 	"strconv"
@@ -26,10 +29,23 @@ var (
 	// as [xml.Decoder.CharsetReader].
 	CharsetReader func(string, io.Reader) (io.Reader, error)
 
+	// timeLayouts also match fractional seconds such as 21:00:12.500, since
+	// [time.Parse] accepts a fraction after the seconds field of any layout.
 	timeLayouts   = []string{iso8601, iso8601Z, iso8601Hyphen, iso8601HyphenZ}
 	errInvalidXML = errors.New("xmlrpc: invalid XML structure")
 )
 
+// Unmarshaler is the interface implemented by types that can decode
+// an XML-RPC value themselves.
+//
+// UnmarshalXMLRPC receives the raw inner XML of the <value> element, that is
+// everything between <value> and </value> exactly as it appears in the
+// document after charset conversion. For <value><int>42</int></value> the data
+// is "<int>42</int>"; for an untyped <value>text</value> it is "text".
+type Unmarshaler interface {
+	UnmarshalXMLRPC(data []byte) error
+}
+
 // TypeMismatchError is returned when the XML-RPC response type does not match
 // the expected Go type during unmarshaling.
 type TypeMismatchError string
@@ -37,17 +53,405 @@ type TypeMismatchError string
 // Error returns the error message describing the type mismatch.
 func (e TypeMismatchError) Error() string { return string(e) }
 
+// decodeOptions holds configuration for decoding XML-RPC values.
+type decodeOptions struct {
+	// thousandsSeparators allows integers like "1,000" to be decoded.
+	thousandsSeparators bool
+	// emptyStructAsNil decodes empty structs into nil maps.
+	emptyStructAsNil bool
+	// richFaults keeps the full fault struct for FaultError.RawMembers.
+	richFaults bool
+	// exponentIntegers allows integers like "1e3" to be decoded.
+	exponentIntegers bool
+	// stringNumbers allows <string> values to be decoded into numeric targets.
+	stringNumbers bool
+	// stringPreprocessor rewrites strings before they are decoded as numbers.
+	stringPreprocessor func(string) string
+	// responseRoot is the element path to the methodResponse element.
+	responseRoot []string
+	// nestedValues unwraps redundant <value> elements nested in a <value>.
+	nestedValues bool
+	// maxDepth limits the nesting depth of values, or defaultMaxDepth if 0.
+	maxDepth int
+	// valueFactory creates the values decoded into nil interfaces.
+	valueFactory ValueFactory
+	// dateTimeLayouts are tried after timeLayouts to parse dateTime values.
+	dateTimeLayouts []string
+	// disallowUnknownFields rejects struct members without a matching field.
+	disallowUnknownFields bool
+	// caseInsensitiveFields matches struct members to fields ignoring case.
+	caseInsensitiveFields bool
+	// truncateArrays allows arrays whose length differs from a Go array target.
+	truncateArrays bool
+	// firstMemberWins keeps the first of duplicate struct members.
+	firstMemberWins bool
+	// lenientNumbers converts between integer and floating-point values.
+	lenientNumbers bool
+	// jsonTags falls back to json struct tags for fields without an xmlrpc tag.
+	jsonTags bool
+}
+
+// defaultMaxDepth is the default maximum nesting depth of decoded values.
+const defaultMaxDepth = 10000
+
+// maxNestedValues is the maximum number of redundant <value> elements
+// unwrapped by [WithNestedValues].
+const maxNestedValues = 8
+
+// WithThousandsSeparators makes the decoder accept integers that use a comma
+// as thousands separator, such as "1,000". Groups must be exactly three digits
+// long, so ambiguous values like "1,00,000" are still rejected.
+func WithThousandsSeparators() Option {
+	return func(o *clientOptions) {
+		o.decode.thousandsSeparators = true
+	}
+}
+
+// WithExponentIntegers makes the decoder accept integers written in exponent
+// notation with an integer mantissa and a non-negative exponent, such as "1e3".
+// Values with a fractional mantissa, like "1.5e1", or that overflow the target
+// type are still rejected.
+func WithExponentIntegers() Option {
+	return func(o *clientOptions) {
+		o.decode.exponentIntegers = true
+	}
+}
+
+// WithStringNumbers makes the decoder accept <string> values holding a number
+// for integer and floating-point targets, e.g. <string>42</string> for an int.
+// Strings that are not valid numbers are still rejected.
+func WithStringNumbers() Option {
+	return func(o *clientOptions) {
+		o.decode.stringNumbers = true
+	}
+}
+
+// WithLenientNumbers makes the decoder convert between integer and
+// floating-point values: <double> values without a fractional part, such as
+// 5.0, decode into integer targets, and integer values into floating-point
+// targets. Doubles with a fractional part or that overflow the target are
+// still rejected with a [TypeMismatchError].
+func WithLenientNumbers() Option {
+	return func(o *clientOptions) {
+		o.decode.lenientNumbers = true
+	}
+}
+
+// WithStringPreprocessor sets a function that rewrites the content of a
+// <string> value before it is decoded into a numeric target with
+// [WithStringNumbers], e.g. to strip units or currency symbols. It has no
+// effect on values decoded into strings.
+func WithStringPreprocessor(fn func(s string) string) Option {
+	return func(o *clientOptions) {
+		o.decode.stringPreprocessor = fn
+	}
+}
+
+// WithResponseRoot sets the slash-separated path of elements leading to the
+// methodResponse element, for responses wrapped in an envelope, e.g.
+// "Envelope/Body/methodResponse". Element names are matched without their
+// namespace, and other elements along the way are skipped. The last element
+// of the path is decoded as the methodResponse, whatever its name.
+// By default the first methodResponse element is decoded.
+func WithResponseRoot(path string) Option {
+	return func(o *clientOptions) {
+		o.decode.responseRoot = strings.Split(strings.Trim(path, "/"), "/")
+	}
+}
+
+// WithNestedValues makes the decoder accept <value> elements that are
+// redundantly wrapped in further <value> elements, such as
+// <value><value><int>1</int></value></value>, as sent by some proxies.
+// At most 8 levels of nesting are unwrapped. By default nested <value>
+// elements are rejected.
+func WithNestedValues() Option {
+	return func(o *clientOptions) {
+		o.decode.nestedValues = true
+	}
+}
+
+// WithMaxDepth sets the maximum nesting depth of arrays and structs the
+// decoder accepts, counted in <value> elements. Deeper documents fail with an
+// error instead of exhausting the stack. Defaults to 10000.
+func WithMaxDepth(n int) Option {
+	return func(o *clientOptions) {
+		o.decode.maxDepth = n
+	}
+}
+
+// ValueFactory returns a pointer to a new value to decode an XML-RPC value of
+// the given type, such as "struct", "array" or "int", into. It is used when
+// decoding into a nil interface, and the value it points to is stored in the
+// interface once decoded. Returning nil selects the default type listed for
+// [Unmarshal].
+type ValueFactory func(typeName string) any
+
+// WithValueFactory sets a [ValueFactory] controlling the types the decoder
+// creates for values decoded into interfaces, at any depth. For example,
+// returning new(OrderedMap) for "struct" keeps struct members in document
+// order, and returning new(map[string]string) decodes structs with only
+// string members into typed maps.
+func WithValueFactory(f ValueFactory) Option {
+	return func(o *clientOptions) {
+		o.decode.valueFactory = f
+	}
+}
+
+// WithDateTimeLayouts adds [time.Parse] layouts for decoding <dateTime.iso8601>
+// values, e.g. "2006/01/02 15:04:05" for servers using a non-standard format.
+// The layouts are tried in order after the built-in ISO 8601 variants, and the
+// first matching layout wins. Can be used multiple times to add more layouts.
+func WithDateTimeLayouts(layouts ...string) Option {
+	return func(o *clientOptions) {
+		o.decode.dateTimeLayouts = append(o.decode.dateTimeLayouts, layouts...)
+	}
+}
+
+// WithEmptyStructAsNil makes the decoder store nil instead of an empty map
+// when an empty <struct> is decoded into a map or interface value. This allows
+// distinguishing an empty struct from an absent one. Struct targets are not
+// affected.
+func WithEmptyStructAsNil() Option {
+	return func(o *clientOptions) {
+		o.decode.emptyStructAsNil = true
+	}
+}
+
+// WithRichFaults makes the decoder keep all members of the fault struct,
+// returned by [FaultError.RawMembers], so extra members sent by the server
+// are not lost.
+func WithRichFaults() Option {
+	return func(o *clientOptions) {
+		o.decode.richFaults = true
+	}
+}
+
+// WithDisallowUnknownFields makes the decoder fail with an error naming the
+// first struct member that has no matching field in the Go struct it is
+// decoded into, e.g. to detect API changes in contract tests. By default such
+// members are skipped. Members decoded into maps are not affected.
+func WithDisallowUnknownFields() Option {
+	return func(o *clientOptions) {
+		o.decode.disallowUnknownFields = true
+	}
+}
+
+// WithCaseInsensitiveFields makes the decoder match struct members to the
+// fields of a Go struct ignoring case, like encoding/json, if no field matches
+// exactly, e.g. the member "UserName" to a field tagged "username". Exact
+// matches always take precedence. By default member names must match exactly.
+func WithCaseInsensitiveFields() Option {
+	return func(o *clientOptions) {
+		o.decode.caseInsensitiveFields = true
+	}
+}
+
+// WithTruncateArrays makes the decoder accept arrays whose number of elements
+// differs from the length of the Go array they are decoded into, e.g. [3]int.
+// Surplus elements are skipped and missing ones are set to the zero value.
+// By default such arrays fail with a [TypeMismatchError]. Slices are not
+// affected.
+func WithTruncateArrays() Option {
+	return func(o *clientOptions) {
+		o.decode.truncateArrays = true
+	}
+}
+
+// WithFirstMemberWins makes the decoder keep the first of several struct
+// members with the same name when decoding into a Go struct or map. By default
+// the last one wins, replacing the value decoded from earlier ones. Members
+// decoded into an [OrderedMap] or []Member are all kept either way.
+func WithFirstMemberWins() Option {
+	return func(o *clientOptions) {
+		o.decode.firstMemberWins = true
+	}
+}
+
+// WithStrict enables all strict decoding checks. Currently this is
+// [WithDisallowUnknownFields].
+func WithStrict() Option {
+	return WithDisallowUnknownFields()
+}
+
 type decoder struct {
 	*xml.Decoder
+	opts decodeOptions
+	// depth is the number of <value> elements currently being decoded.
+	depth int
+	// next and nextErr hold the token or error read ahead by Token.
+	next    xml.Token
+	nextErr error
+	// charData holds the character data returned by Token.
+	charData []byte
 }
 
-func unmarshal(data []byte, v any) (err error) {
-	dec := &decoder{xml.NewDecoder(bytes.NewBuffer(data))}
+func newDecoder(r io.Reader, opts decodeOptions) *decoder {
+	dec := &decoder{Decoder: xml.NewDecoder(r), opts: opts}
 
 	if CharsetReader != nil {
 		dec.CharsetReader = CharsetReader
 	}
 
+	return dec
+}
+
+// Token returns the next XML token like [xml.Decoder.Token], but skips
+// comments, processing instructions and directives, so they may appear
+// anywhere in a document. Character data split by them, or by CDATA sections,
+// is returned as a single token.
+func (dec *decoder) Token() (xml.Token, error) {
+	tok, err := dec.token()
+	if err != nil {
+		return nil, err
+	}
+	data, ok := tok.(xml.CharData)
+	if !ok {
+		return tok, nil
+	}
+
+	// The data is only valid until the next token is read, so it is kept in
+	// a buffer that stays valid until the next call instead, like the data
+	// returned by [xml.Decoder.Token].
+	dec.charData = append(dec.charData[:0], data...)
+	for {
+		tok, err = dec.token()
+		if err != nil {
+			dec.nextErr = err
+			return xml.CharData(dec.charData), nil
+		}
+		more, ok := tok.(xml.CharData)
+		if !ok {
+			dec.next = tok
+			return xml.CharData(dec.charData), nil
+		}
+		dec.charData = append(dec.charData, more...)
+	}
+}
+
+// token returns the token read ahead, if any, or the next token other than a
+// comment, processing instruction or directive.
+func (dec *decoder) token() (xml.Token, error) {
+	if tok := dec.next; tok != nil {
+		dec.next = nil
+		return tok, nil
+	}
+	if dec.nextErr != nil {
+		return nil, dec.nextErr
+	}
+	for {
+		tok, err := dec.Decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.(type) {
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			continue
+		}
+		return tok, nil
+	}
+}
+
+// Skip reads tokens until it has consumed the end element matching the most
+// recent start element, like [xml.Decoder.Skip], taking tokens read ahead by
+// Token into account.
+func (dec *decoder) Skip() error {
+	depth := 0
+	for {
+		tok, err := dec.token()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return nil
+			}
+			depth--
+		}
+	}
+}
+
+// Unmarshal decodes XML-RPC data into the value pointed to by v.
+// The data may be either a bare <value> element or a complete <methodResponse>
+// document. If the response contains a fault, Unmarshal returns a [FaultError].
+// Unmarshal returns an error if v is nil or not a pointer.
+//
+// When decoding into an interface value, all integer types (<int>, <i1>,
+// <i2>, <i4> and <i8>) are stored as int64, <double> as float64, arrays as
+// []any and structs as map[string]any.
+//
+// Decoding options such as [WithThousandsSeparators] may be passed in opts;
+// options unrelated to decoding are ignored.
+func Unmarshal(data []byte, v any, opts ...Option) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Pointer || val.IsNil() {
+		return fmt.Errorf("xmlrpc: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+
+	options := &clientOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return err
+	}
+
+	dec := newDecoder(bytes.NewReader(data), options.decode)
+	if root == "methodResponse" || options.decode.responseRoot != nil {
+		return dec.unmarshalResponse(v)
+	}
+	return dec.unmarshal(v)
+}
+
+// memberBindings maps struct member names to the pointers they are decoded into.
+type memberBindings map[string]any
+
+// BindMembers decodes selected members of the struct in data into the
+// pointers bound to their names in bindings, e.g.
+//
+//	err := xmlrpc.BindMembers(resp.Raw, map[string]any{"user": &u, "count": &n})
+//
+// Like [Unmarshal], data may be either a bare <value> element or a complete
+// <methodResponse> document such as [CallResponse.Raw], a fault is returned as
+// a [FaultError], and decoding options may be passed in opts. Members without a
+// binding are ignored and bound members missing from data leave their pointer
+// unchanged. An error decoding a member names that member.
+func BindMembers(data []byte, bindings map[string]any, opts ...Option) error {
+	for name, p := range bindings {
+		if val := reflect.ValueOf(p); val.Kind() != reflect.Pointer || val.IsNil() {
+			return fmt.Errorf("xmlrpc: BindMembers requires non-nil pointers, got %T for member %q", p, name)
+		}
+	}
+	b := memberBindings(bindings)
+	return Unmarshal(data, &b, opts...)
+}
+
+// rootElement returns the local name of the first element in data.
+func rootElement(data []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = CharsetReader
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		if t, ok := tok.(xml.StartElement); ok {
+			return t.Name.Local, nil
+		}
+	}
+}
+
+func unmarshal(data []byte, v any) error {
+	return newDecoder(bytes.NewReader(data), decodeOptions{}).unmarshal(v)
+}
+
+// unmarshal decodes the first <value> element into v.
+func (dec *decoder) unmarshal(v any) (err error) {
 	var tok xml.Token
 	for {
 		if tok, err = dec.Token(); err != nil {
@@ -78,45 +482,20 @@ func unmarshal(data []byte, v any) (err error) {
 	return nil
 }
 
-// unmarshalResponse decodes a full methodResponse from r, handling faults.
+// unmarshalResponse decodes a full methodResponse, handling faults.
 // If the response is a fault, it returns a FaultError.
-func unmarshalResponse(r io.Reader, v any) (err error) {
-	dec := &decoder{xml.NewDecoder(r)}
-
-	if CharsetReader != nil {
-		dec.CharsetReader = CharsetReader
-	}
-
-	// Find methodResponse
+func (dec *decoder) unmarshalResponse(v any) (err error) {
 	var tok xml.Token
-	for {
-		if tok, err = dec.Token(); err != nil {
-			return err
-		}
-		if t, ok := tok.(xml.StartElement); ok {
-			if t.Name.Local == "methodResponse" {
-				break
-			}
-		}
+	isFault, err := dec.readResponseStart()
+	if err != nil {
+		return err
 	}
-
-	// Next element is either <fault> or <params>
-	for {
-		if tok, err = dec.Token(); err != nil {
-			return err
-		}
-		if t, ok := tok.(xml.StartElement); ok {
-			if t.Name.Local == "fault" {
-				var fault FaultError
-				if err = dec.decodeFaultValue(&fault); err != nil {
-					return fmt.Errorf("xmlrpc: failed to parse fault response: %w", err)
-				}
-				return fault
-			}
-			if t.Name.Local == "params" {
-				break
-			}
+	if isFault {
+		var fault FaultError
+		if err = dec.decodeFaultValue(&fault); err != nil {
+			return fmt.Errorf("xmlrpc: failed to parse fault response: %w", err)
 		}
+		return fault
 	}
 
 	// Find first <value> in params
@@ -126,6 +505,12 @@ func unmarshalResponse(r io.Reader, v any) (err error) {
 		}
 		if t, ok := tok.(xml.StartElement); ok {
 			if t.Name.Local == "value" {
+				if stream, ok := v.(arrayStream); ok {
+					if err = dec.decodeArrayStream(stream); err != nil {
+						return err
+					}
+					break
+				}
 				val := reflect.ValueOf(v)
 				if val.Kind() != reflect.Pointer {
 					return fmt.Errorf("xmlrpc: non-pointer value passed to unmarshal")
@@ -147,6 +532,167 @@ func unmarshalResponse(r io.Reader, v any) (err error) {
 	return nil
 }
 
+// decodeMethodCall decodes a <methodCall> document into its method name and
+// parameters, each decoded as described for [Unmarshal]. params is nil if
+// the call has no <params> element.
+func (dec *decoder) decodeMethodCall() (method string, params []any, err error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", nil, err
+		}
+		if t, ok := tok.(xml.StartElement); ok {
+			if t.Name.Local != "methodCall" {
+				return "", nil, fmt.Errorf("xmlrpc: unexpected root element %q, expected methodCall", t.Name.Local)
+			}
+			break
+		}
+	}
+
+	hasName := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "methodName":
+				name, err := dec.readCharData()
+				if err != nil {
+					return "", nil, err
+				}
+				method, hasName = strings.TrimSpace(string(name)), true
+				// </methodName>
+				if err = dec.Skip(); err != nil {
+					return "", nil, err
+				}
+			case "params":
+				if params, err = dec.decodeParams(); err != nil {
+					return "", nil, err
+				}
+			default:
+				return "", nil, fmt.Errorf("xmlrpc: unexpected element %q in methodCall", t.Name.Local)
+			}
+		case xml.EndElement:
+			if !hasName {
+				return "", nil, fmt.Errorf("xmlrpc: missing methodName in methodCall")
+			}
+			return method, params, nil
+		}
+	}
+}
+
+// decodeParams decodes the <param> elements of a <params> element. It
+// consumes the closing </params>.
+func (dec *decoder) decodeParams() ([]any, error) {
+	params := []any{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "param" {
+				return nil, errInvalidXML
+			}
+			start, err := dec.nextStart()
+			if err != nil {
+				return nil, err
+			}
+			if start.Name.Local != "value" {
+				return nil, errInvalidXML
+			}
+
+			var param any
+			if err = dec.decodeValue(reflect.ValueOf(&param).Elem()); err != nil {
+				return nil, fmt.Errorf("xmlrpc: param %d: %w", len(params), err)
+			}
+			params = append(params, param)
+
+			// </param>
+			if err = dec.Skip(); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			return params, nil
+		}
+	}
+}
+
+// readResponseStart reads up to and including the first child of the
+// methodResponse element and reports whether it is <fault> rather than
+// <params>. Only elements are considered, so text that looks like a fault
+// is never mistaken for one.
+func (dec *decoder) readResponseStart() (bool, error) {
+	if dec.opts.responseRoot != nil {
+		if err := dec.findPath(dec.opts.responseRoot); err != nil {
+			return false, err
+		}
+	} else {
+		// Find methodResponse
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return false, err
+			}
+			if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "methodResponse" {
+				break
+			}
+		}
+	}
+
+	start, err := dec.nextStart()
+	if err != nil {
+		return false, err
+	}
+	switch start.Name.Local {
+	case "fault":
+		return true, nil
+	case "params":
+		return false, nil
+	default:
+		return false, fmt.Errorf("xmlrpc: unexpected element %q in methodResponse", start.Name.Local)
+	}
+}
+
+// findPath reads up to and including the start element reached by following
+// path from the current position, skipping elements that do not match.
+func (dec *decoder) findPath(path []string) error {
+	for _, name := range path {
+	FindLoop:
+		for {
+			tok, err := dec.Token()
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("xmlrpc: response element %q not found", strings.Join(path, "/"))
+			}
+			if err != nil {
+				return err
+			}
+
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == name {
+					break FindLoop
+				}
+				if err = dec.Skip(); err != nil {
+					return err
+				}
+			case xml.EndElement:
+				return fmt.Errorf("xmlrpc: response element %q not found", strings.Join(path, "/"))
+			}
+		}
+	}
+	return nil
+}
+
+// decodeFaultValue decodes the <value> of a <fault> element into fault.
+// The fault code may be sent as <int>, <i4>, <i8> or as a <string> holding
+// an integer; see [FaultError.Code64].
 func (dec *decoder) decodeFaultValue(fault *FaultError) error {
 	var tok xml.Token
 	var err error
@@ -155,21 +701,149 @@ func (dec *decoder) decodeFaultValue(fault *FaultError) error {
 			return err
 		}
 		if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "value" {
-			val := reflect.ValueOf(fault).Elem()
-			return dec.decodeValue(val)
+			var raw map[string]any
+			if err = dec.decodeValue(reflect.ValueOf(&raw).Elem()); err != nil {
+				return err
+			}
+
+			switch code := raw["faultCode"].(type) {
+			case nil:
+			case int64:
+				fault.setCode(code)
+			case string:
+				n, err := strconv.ParseInt(strings.TrimSpace(code), 10, 64)
+				if err != nil {
+					return fmt.Errorf("xmlrpc: invalid fault code %q", code)
+				}
+				fault.setCode(n)
+			default:
+				return fmt.Errorf("xmlrpc: invalid fault code type %T", code)
+			}
+			if str, ok := raw["faultString"].(string); ok {
+				fault.String = str
+			}
+			if dec.opts.richFaults {
+				fault.raw = &raw
+			}
+			return nil
+		}
+	}
+}
+
+// arrayStream is passed as reply to [decoder.unmarshalResponse] to decode
+// the elements of an array result one at a time. It is called after the
+// <value> start element of each array element has been read.
+type arrayStream func(dec *decoder) error
+
+// decodeArrayStream decodes the array in the current <value> element by
+// calling stream for each of its elements. It consumes the closing </value>.
+func (dec *decoder) decodeArrayStream(stream arrayStream) error {
+	for _, name := range []string{"array", "data"} {
+		start, err := dec.nextStart()
+		if err != nil {
+			return err
+		}
+		if start.Name.Local != name {
+			return TypeMismatchError(
+				fmt.Sprintf("xmlrpc: cannot stream %s, expected array", start.Name.Local),
+			)
+		}
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "value" {
+				return errInvalidXML
+			}
+			if err = stream(dec); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			// </array>
+			if err = dec.Skip(); err != nil {
+				return err
+			}
+			// </value>
+			return dec.Skip()
 		}
 	}
 }
 
+// nextStart returns the next start element, skipping character data.
+// It fails if an end element is found first.
+func (dec *decoder) nextStart() (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, errInvalidXML
+		}
+	}
+}
+
+// decodeValue decodes the content of a <value> element into val. It must be
+// called after the <value> start element has been read and consumes the
+// matching </value> end element.
 func (dec *decoder) decodeValue(val reflect.Value) error {
+	return dec.decodeValueOrElem(val, false)
+}
+
+// decodeValueOrElem is like decodeValue, but if asElem is set and val is a
+// slice other than []byte, a scalar value is decoded as a single-element slice.
+// This is used for map members such as those of [url.Values].
+func (dec *decoder) decodeValueOrElem(val reflect.Value, asElem bool) (err error) {
 	var tok xml.Token
-	var err error
 
+	maxDepth := dec.opts.maxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	if dec.depth++; dec.depth > maxDepth {
+		return fmt.Errorf("xmlrpc: values nested deeper than %d levels", maxDepth)
+	}
+	defer func() { dec.depth-- }()
+
+	// unwrapped counts redundant nested <value> elements whose end elements
+	// are consumed once the innermost value is decoded.
+	unwrapped := 0
+	defer func() {
+		for ; err == nil && unwrapped > 0; unwrapped-- {
+			err = dec.Skip()
+		}
+	}()
+
+	// ptr is the pointer val was dereferenced from, reset by <nil/>.
+	var ptr reflect.Value
 	if val.Kind() == reflect.Pointer {
 		if val.IsNil() {
 			val.Set(reflect.New(val.Type().Elem()))
 		}
-		val = val.Elem()
+		ptr, val = val, val.Elem()
+	}
+
+	if val.CanAddr() {
+		if u, ok := val.Addr().Interface().(Unmarshaler); ok {
+			var raw struct {
+				Inner []byte `xml:",innerxml"`
+			}
+			start := xml.StartElement{Name: xml.Name{Local: "value"}}
+			if err = dec.DecodeElement(&raw, &start); err != nil {
+				return err
+			}
+			return u.UnmarshalXMLRPC(raw.Inner)
+		}
 	}
 
 	var typeName string
@@ -187,6 +861,12 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 		}
 
 		if t, ok := tok.(xml.StartElement); ok {
+			if t.Name.Local == "value" && dec.opts.nestedValues {
+				if unwrapped++; unwrapped > maxNestedValues {
+					return fmt.Errorf("xmlrpc: more than %d nested value elements", maxNestedValues)
+				}
+				continue
+			}
 			typeName = t.Name.Local
 			break
 		}
@@ -194,18 +874,84 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 		// Treat value data without type identifier as string
 		if t, ok := tok.(xml.CharData); ok {
 			if value := strings.TrimSpace(string(t)); value != "" {
-				if err = checkType(val, reflect.String); err != nil {
+				target := val
+				if asElem && isElemSlice(val) {
+					target = reflect.New(val.Type().Elem()).Elem()
+				}
+				if tu, ok := textUnmarshaler(target); ok {
+					if err = tu.UnmarshalText([]byte(value)); err != nil {
+						return err
+					}
+				} else if err = checkType(target, reflect.String); err != nil {
 					return err
+				} else {
+					target.SetString(value)
+				}
+				if target != val {
+					val.Set(reflect.Append(reflect.MakeSlice(val.Type(), 0, 1), target))
 				}
 
-				val.SetString(value)
-				return nil
+				// </value>
+				return dec.Skip()
 			}
 		}
 	}
 
+	if dec.opts.valueFactory != nil && val.Kind() == reflect.Interface && val.IsNil() {
+		if p := dec.opts.valueFactory(typeName); p != nil {
+			pv := reflect.ValueOf(p)
+			if pv.Kind() != reflect.Pointer || pv.IsNil() {
+				return fmt.Errorf("xmlrpc: value factory returned %T, want non-nil pointer", p)
+			}
+			if !pv.Elem().Type().AssignableTo(val.Type()) {
+				return TypeMismatchError(fmt.Sprintf(
+					"xmlrpc: cannot assign %s from value factory to %s", pv.Elem().Type(), val.Type()))
+			}
+			iface, target := val, pv.Elem()
+			defer func() {
+				if err == nil {
+					iface.Set(target)
+				}
+			}()
+			val = target
+		}
+	}
+
+	// Decode scalars into a new element and append it once decoded.
+	elemSlice := reflect.Value{}
+	if asElem && typeName != "array" && isElemSlice(val) {
+		elemSlice = val
+		val = reflect.New(val.Type().Elem()).Elem()
+	}
+
 	switch typeName {
+	case "nil":
+		// The <nil/> extension, also sent as <ex:nil/> by Apache XML-RPC.
+		if ptr.IsValid() && ptr.CanSet() {
+			ptr.Set(reflect.Zero(ptr.Type()))
+		} else {
+			val.Set(reflect.Zero(val.Type()))
+		}
+		elemSlice = reflect.Value{}
+
+		// </nil>
+		if err = dec.Skip(); err != nil {
+			return err
+		}
 	case "struct":
+		if t := val.Type(); t == reflect.TypeFor[[]Member]() || t == reflect.TypeFor[OrderedMap]() {
+			if err = dec.decodeMembers(val); err != nil {
+				return err
+			}
+			break
+		}
+		if b, ok := val.Interface().(memberBindings); ok {
+			if err = dec.decodeBindings(b); err != nil {
+				return err
+			}
+			break
+		}
+
 		ismap := false
 		pmap := val
 		valType := val.Type()
@@ -229,33 +975,25 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 			}
 		}
 
-		var fields map[string]reflect.Value
+		var fieldList []structField
+		var fields map[string]structField
 
 		if !ismap {
-			fields = make(map[string]reflect.Value)
-
-			for i := 0; i < valType.NumField(); i++ {
-				field := valType.Field(i)
-				fieldVal := val.FieldByName(field.Name)
-
-				if fieldVal.CanSet() {
-					name := field.Tag.Get("xmlrpc")
-					name = strings.TrimSuffix(name, ",omitempty")
-					if name == "-" {
-						continue
-					}
-					if name == "" {
-						name = field.Name
-					}
-					fields[name] = fieldVal
-				}
+			fieldList = structFields(valType, dec.opts.jsonTags)
+			fields = make(map[string]structField, len(fieldList))
+			for _, field := range fieldList {
+				fields[field.name] = field
 			}
 		} else {
 			// Create initial empty map
 			pmap.Set(reflect.MakeMap(valType))
+			val.Set(pmap)
 		}
 
-		// Process struct members.
+		// Process struct members. seen holds the fields or map keys already
+		// decoded, to resolve duplicate members.
+		members := 0
+		seen := make(map[string]bool)
 	StructLoop:
 		for {
 			if tok, err = dec.Token(); err != nil {
@@ -266,6 +1004,7 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 				if t.Name.Local != "member" {
 					return errInvalidXML
 				}
+				members++
 
 				tagName, fieldName, err := dec.readTag()
 				if err != nil {
@@ -277,25 +1016,42 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 
 				var fv reflect.Value
 				ok := true
+				key := string(fieldName)
 
 				if !ismap {
-					fv, ok = fields[string(fieldName)]
+					field, found := fields[key]
+					if !found && dec.opts.caseInsensitiveFields {
+						field, found = foldField(fieldList, key)
+					}
+					if ok = found; ok {
+						fv = settableField(val, field.index)
+						ok = fv.IsValid()
+					}
+					if !ok && dec.opts.disallowUnknownFields {
+						return fmt.Errorf("xmlrpc: unknown struct member %q for %s", fieldName, valType)
+					}
+					key = field.name
 				} else {
 					fv = reflect.New(valType.Elem())
 				}
 
+				if ok && seen[key] {
+					if dec.opts.firstMemberWins {
+						ok = false
+					} else if !ismap {
+						// Replace rather than merge into the earlier value.
+						fv.SetZero()
+					}
+				}
+				seen[key] = true
+
 				if ok {
 					for {
 						if tok, err = dec.Token(); err != nil {
 							return err
 						}
 						if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "value" {
-							if err = dec.decodeValue(fv); err != nil {
-								return err
-							}
-
-							// </value>
-							if err = dec.Skip(); err != nil {
+							if err = dec.decodeValueOrElem(fv, ismap); err != nil {
 								return err
 							}
 
@@ -309,7 +1065,7 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 					return err
 				}
 
-				if ismap {
+				if ismap && ok {
 					pmap.SetMapIndex(reflect.ValueOf(string(fieldName)), reflect.Indirect(fv))
 					val.Set(pmap)
 				}
@@ -317,7 +1073,18 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 				break StructLoop
 			}
 		}
+
+		if ismap && members == 0 && dec.opts.emptyStructAsNil {
+			val.Set(reflect.Zero(val.Type()))
+		}
 	case "array":
+		if val.Kind() == reflect.Array {
+			if err = dec.decodeFixedArray(val); err != nil {
+				return err
+			}
+			break
+		}
+
 		slice := val
 		if checkType(val, reflect.Interface) == nil && val.IsNil() {
 			slice = reflect.ValueOf([]any{})
@@ -368,10 +1135,6 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 							slice = reflect.Append(slice, v.Elem())
 						}
 
-						// </value>
-						if err = dec.Skip(); err != nil {
-							return err
-						}
 						index++
 					case xml.EndElement:
 						val.Set(slice)
@@ -388,10 +1151,18 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 		}
 
 		var data []byte
+		closed := false
 
 		switch t := tok.(type) {
 		case xml.EndElement:
-			return nil
+			// Empty integer and boolean elements decode to their zero value.
+			switch typeName {
+			case "int", "i1", "i2", "i4", "i8", "boolean":
+				closed = true
+			default:
+				// </value>
+				return dec.Skip()
+			}
 		case xml.CharData:
 			data = []byte(t.Copy())
 		default:
@@ -399,9 +1170,9 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 		}
 
 		switch typeName {
-		case "int", "i4", "i8":
+		case "int", "i1", "i2", "i4", "i8":
 			if checkType(val, reflect.Interface) == nil && val.IsNil() {
-				i, err := strconv.ParseInt(string(data), 10, 64)
+				i, err := dec.parseInt(data, intBits(typeName, 64))
 				if err != nil {
 					return err
 				}
@@ -409,37 +1180,66 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 				pi := reflect.New(reflect.TypeFor[int64]()).Elem()
 				pi.SetInt(i)
 				val.Set(pi)
+			} else if tu, ok := textUnmarshaler(val); ok {
+				if err = tu.UnmarshalText(bytes.TrimSpace(data)); err != nil {
+					return err
+				}
+			} else if dec.opts.lenientNumbers && checkType(val, reflect.Float32, reflect.Float64) == nil {
+				i, err := dec.parseInt(data, intBits(typeName, 64))
+				if err != nil {
+					return err
+				}
+
+				val.SetFloat(float64(i))
 			} else if err = checkType(val, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64); err != nil {
 				return err
 			} else {
-				i, err := strconv.ParseInt(string(data), 10, val.Type().Bits())
+				i, err := dec.parseInt(data, intBits(typeName, val.Type().Bits()))
 				if err != nil {
 					return err
 				}
 
 				val.SetInt(i)
 			}
-		case "string", "base64":
+		case "base64":
+			if val.Kind() == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8 {
+				b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+				if err != nil {
+					return fmt.Errorf("xmlrpc: failed to decode base64 value: %w", err)
+				}
+				val.SetBytes(b)
+				break
+			}
+			fallthrough
+		case "string":
 			str := string(data)
 			if checkType(val, reflect.Interface) == nil && val.IsNil() {
 				pstr := reflect.New(reflect.TypeFor[string]()).Elem()
 				pstr.SetString(str)
 				val.Set(pstr)
+			} else if tu, ok := textUnmarshaler(val); ok && typeName == "string" {
+				if err = tu.UnmarshalText(data); err != nil {
+					return err
+				}
+			} else if typeName == "string" && dec.opts.stringNumbers && isNumeric(val) {
+				if err = dec.decodeStringNumber(val, str); err != nil {
+					return err
+				}
 			} else if err = checkType(val, reflect.String); err != nil {
 				return err
 			} else {
 				val.SetString(str)
 			}
-		case "dateTime.iso8601":
-			var t time.Time
-			var err error
-
-			for _, layout := range timeLayouts {
-				t, err = time.Parse(layout, string(data))
-				if err == nil {
-					break
-				}
+		case "dateTime.iso8601", "dateTime":
+			// String targets receive the text verbatim, whether or not it
+			// parses as a time.
+			if val.Kind() == reflect.String {
+				val.SetString(string(data))
+				break
 			}
+
+			// Some servers omit the .iso8601 suffix.
+			t, err := dec.parseTime(string(data))
 			if err != nil {
 				return err
 			}
@@ -456,9 +1256,11 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 				val.Set(reflect.ValueOf(t))
 			}
 		case "boolean":
-			v, err := strconv.ParseBool(string(data))
-			if err != nil {
-				return err
+			var v bool
+			if str := strings.TrimSpace(string(data)); str != "" {
+				if v, err = strconv.ParseBool(str); err != nil {
+					return err
+				}
 			}
 
 			if checkType(val, reflect.Interface) == nil && val.IsNil() {
@@ -480,6 +1282,11 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 				pdouble := reflect.New(reflect.TypeFor[float64]()).Elem()
 				pdouble.SetFloat(i)
 				val.Set(pdouble)
+			} else if dec.opts.lenientNumbers &&
+				checkType(val, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64) == nil {
+				if err = decodeIntegralDouble(val, string(data)); err != nil {
+					return err
+				}
 			} else if err = checkType(val, reflect.Float32, reflect.Float64); err != nil {
 				return err
 			} else {
@@ -495,12 +1302,237 @@ func (dec *decoder) decodeValue(val reflect.Value) error {
 		}
 
 		// </type>
-		if err = dec.Skip(); err != nil {
+		if !closed {
+			if err = dec.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if elemSlice.IsValid() {
+		elemSlice.Set(reflect.Append(reflect.MakeSlice(elemSlice.Type(), 0, 1), val))
+	}
+
+	// </value>
+	return dec.Skip()
+}
+
+// foldField returns the first field in fields whose name equals name
+// ignoring case.
+func foldField(fields []structField, name string) (structField, bool) {
+	for _, field := range fields {
+		if strings.EqualFold(field.name, name) {
+			return field, true
+		}
+	}
+	return structField{}, false
+}
+
+// settableField returns the field of struct val at index, allocating nil
+// embedded struct pointers on the way. It returns the zero Value if the field
+// cannot be set, e.g. because it is unexported.
+func settableField(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Pointer {
+			if val.IsNil() {
+				if !val.CanSet() {
+					return reflect.Value{}
+				}
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	if !val.CanSet() {
+		return reflect.Value{}
+	}
+	return val
+}
+
+// decodeMembers decodes the members of a <struct> into val, a []Member or
+// [OrderedMap], keeping their order. It consumes the closing </struct>.
+func (dec *decoder) decodeMembers(val reflect.Value) error {
+	members := []Member{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
 			return err
 		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "member" {
+				return errInvalidXML
+			}
+
+			tagName, name, err := dec.readTag()
+			if err != nil {
+				return err
+			}
+			if tagName != "name" {
+				return errInvalidXML
+			}
+
+			m := Member{Name: string(name)}
+			start, err := dec.nextStart()
+			if err != nil {
+				return err
+			}
+			if start.Name.Local != "value" {
+				return errInvalidXML
+			}
+			if err = dec.decodeValue(reflect.ValueOf(&m.Value).Elem()); err != nil {
+				return err
+			}
+			members = append(members, m)
+
+			// </member>
+			if err = dec.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			val.Set(reflect.ValueOf(members).Convert(val.Type()))
+			return nil
+		}
 	}
+}
 
-	return nil
+// decodeBindings decodes the members of a <struct> into the pointers bound
+// to their names, skipping unbound members. It consumes the closing </struct>.
+func (dec *decoder) decodeBindings(bindings memberBindings) error {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "member" {
+				return errInvalidXML
+			}
+
+			tagName, name, err := dec.readTag()
+			if err != nil {
+				return err
+			}
+			if tagName != "name" {
+				return errInvalidXML
+			}
+
+			if p, ok := bindings[string(name)]; ok {
+				start, err := dec.nextStart()
+				if err != nil {
+					return err
+				}
+				if start.Name.Local != "value" {
+					return errInvalidXML
+				}
+				if err = dec.decodeValue(reflect.ValueOf(p).Elem()); err != nil {
+					return fmt.Errorf("xmlrpc: member %q: %w", name, err)
+				}
+			}
+
+			// </member>
+			if err = dec.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodeFixedArray decodes the elements of an <array> into val, a Go array.
+// Unless [WithTruncateArrays] is used, the number of elements must match the
+// length of val. It consumes the closing </array>.
+func (dec *decoder) decodeFixedArray(val reflect.Value) error {
+	n := 0
+	inData := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !inData {
+				if t.Name.Local != "data" {
+					return errInvalidXML
+				}
+				inData = true
+				continue
+			}
+			if t.Name.Local != "value" {
+				return errInvalidXML
+			}
+
+			if n < val.Len() {
+				err = dec.decodeValue(val.Index(n))
+			} else if dec.opts.truncateArrays {
+				err = dec.Skip()
+			} else {
+				return TypeMismatchError(fmt.Sprintf(
+					"xmlrpc: cannot unmarshal array of more than %d elements to %s", val.Len(), val.Type()))
+			}
+			if err != nil {
+				return err
+			}
+			n++
+		case xml.EndElement:
+			if inData {
+				inData = false
+				continue
+			}
+			if n < val.Len() {
+				if !dec.opts.truncateArrays {
+					return TypeMismatchError(fmt.Sprintf(
+						"xmlrpc: cannot unmarshal array of %d elements to %s", n, val.Type()))
+				}
+				for i := n; i < val.Len(); i++ {
+					val.Index(i).SetZero()
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// parseTime parses s using the built-in layouts followed by those set with
+// [WithDateTimeLayouts]. The first matching layout wins.
+func (dec *decoder) parseTime(s string) (time.Time, error) {
+	var t time.Time
+	var err error
+	for _, layout := range timeLayouts {
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range dec.opts.dateTimeLayouts {
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// textUnmarshaler returns the [encoding.TextUnmarshaler] implemented by a
+// pointer to val, if val is addressable. [time.Time] is excluded so that
+// strings are not decoded into times.
+func textUnmarshaler(val reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if !val.CanAddr() || val.Type() == reflect.TypeFor[time.Time]() {
+		return nil, false
+	}
+	tu, ok := val.Addr().Interface().(encoding.TextUnmarshaler)
+	return tu, ok
+}
+
+// isElemSlice reports whether val is a slice that can hold a single decoded
+// value, excluding []byte which holds base64 data.
+func isElemSlice(val reflect.Value) bool {
+	return val.Kind() == reflect.Slice && val.Type().Elem().Kind() != reflect.Uint8
 }
 
 func (dec *decoder) readTag() (string, []byte, error) {
@@ -540,6 +1572,123 @@ func (dec *decoder) readCharData() ([]byte, error) {
 	return bytes.Clone(t), nil
 }
 
+// parseInt parses the content of an integer element, applying the lenient
+// parsing enabled in the decoder options.
+func (dec *decoder) parseInt(data []byte, bitSize int) (int64, error) {
+	if dec.opts.thousandsSeparators {
+		data = stripThousandsSeparators(data)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	i, err := strconv.ParseInt(string(data), 10, bitSize)
+	if err == nil || !dec.opts.exponentIntegers {
+		return i, err
+	}
+
+	// Fall back to exponent notation such as "1e3".
+	mantissa, exponent, ok := strings.Cut(strings.ToLower(string(data)), "e")
+	if !ok {
+		return 0, err
+	}
+	m, merr := strconv.ParseInt(mantissa, 10, bitSize)
+	e, eerr := strconv.ParseUint(exponent, 10, 8)
+	if merr != nil || eerr != nil {
+		return 0, fmt.Errorf("xmlrpc: cannot decode %q as integer", data)
+	}
+	limit := int64(math.MaxInt64 >> (64 - bitSize))
+	for ; e > 0; e-- {
+		if m > limit/10 || m < -limit/10 {
+			return 0, fmt.Errorf("xmlrpc: integer %q overflows %d bits", data, bitSize)
+		}
+		m *= 10
+	}
+	return m, nil
+}
+
+// intBits returns the number of bits an integer element of the given type
+// may use when decoded into a target of targetBits. The <i1> and <i2>
+// aliases sent by some embedded servers are limited to 8 and 16 bits.
+func intBits(typeName string, targetBits int) int {
+	switch typeName {
+	case "i1":
+		return min(8, targetBits)
+	case "i2":
+		return min(16, targetBits)
+	}
+	return targetBits
+}
+
+// decodeIntegralDouble decodes the content of a <double> element into val,
+// an integer, failing unless it is a whole number that fits into val.
+func decodeIntegralDouble(val reflect.Value, str string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil {
+		return err
+	}
+	// -2^63 converts exactly, while 2^63 is the first value above MaxInt64.
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= -math.MinInt64 || val.OverflowInt(int64(f)) {
+		return TypeMismatchError(fmt.Sprintf("xmlrpc: cannot decode double %s to %v", str, val.Kind()))
+	}
+	val.SetInt(int64(f))
+	return nil
+}
+
+// isNumeric reports whether val is a signed integer or floating-point value.
+func isNumeric(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// decodeStringNumber decodes the content of a <string> value into the numeric
+// val, applying the string preprocessor first.
+func (dec *decoder) decodeStringNumber(val reflect.Value, str string) error {
+	if dec.opts.stringPreprocessor != nil {
+		str = dec.opts.stringPreprocessor(str)
+	}
+	str = strings.TrimSpace(str)
+
+	switch val.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, val.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("xmlrpc: cannot decode string %q as number: %w", str, err)
+		}
+		val.SetFloat(f)
+	default:
+		if str == "" {
+			return fmt.Errorf("xmlrpc: cannot decode empty string as number")
+		}
+		i, err := dec.parseInt([]byte(str), val.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("xmlrpc: cannot decode string %q as number: %w", str, err)
+		}
+		val.SetInt(i)
+	}
+	return nil
+}
+
+// stripThousandsSeparators removes comma thousands separators from an integer.
+// If data is not a correctly grouped integer it is returned unchanged.
+func stripThousandsSeparators(data []byte) []byte {
+	digits := strings.TrimPrefix(string(data), "-")
+	groups := strings.Split(digits, ",")
+	if len(groups) < 2 || len(groups[0]) == 0 || len(groups[0]) > 3 {
+		return data
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 {
+			return data
+		}
+	}
+	return bytes.ReplaceAll(data, []byte(","), nil)
+}
+
 func checkType(val reflect.Value, kinds ...reflect.Kind) error {
 	if len(kinds) == 0 {
 		return nil
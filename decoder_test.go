@@ -2,10 +2,16 @@ package xmlrpc
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"golang.org/x/text/encoding/charmap"
@@ -22,6 +28,50 @@ type bookUnexported struct {
 	amount int    //lint:ignore U1000 intentionally unexported for testing unmarshalling behavior
 }
 
+// flexibleID accepts both <string> and <int> representations.
+type flexibleID struct {
+	ID  string
+	Raw string
+}
+
+func (f *flexibleID) UnmarshalXMLRPC(data []byte) error {
+	f.Raw = string(data)
+
+	var v any
+	if err := unmarshal([]byte("<value>"+string(data)+"</value>"), &v); err != nil {
+		return err
+	}
+	switch id := v.(type) {
+	case string:
+		f.ID = id
+	case int64:
+		f.ID = strconv.FormatInt(id, 10)
+	default:
+		return fmt.Errorf("unexpected id type %T", v)
+	}
+	return nil
+}
+
+// priority is an enum implementing encoding.TextUnmarshaler.
+type priority int
+
+const (
+	priorityLow priority = iota + 1
+	priorityHigh
+)
+
+func (p *priority) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low", "1":
+		*p = priorityLow
+	case "high", "2":
+		*p = priorityHigh
+	default:
+		return fmt.Errorf("unknown priority %q", text)
+	}
+	return nil
+}
+
 func testTime(year int, month time.Month, day, hour, min, sec int, loc *time.Location) time.Time {
 	return time.Date(year, month, day, hour, min, sec, 0, loc)
 }
@@ -32,11 +82,16 @@ var unmarshalTests = []struct {
 	ptr   any
 	xml   string
 }{
-	// int, i4, i8
+	// int, i1, i2, i4, i8
 	{"int/empty", 0, new(*int), "<value><int></int></value>"},
+	{"int/self_closing", 0, new(*int), "<value><int/></value>"},
 	{"int/positive", 100, new(*int), "<value><int>100</int></value>"},
 	{"i4", 389451, new(*int), "<value><i4>389451</i4></value>"},
 	{"i8", int64(45659074), new(*int64), "<value><i8>45659074</i8></value>"},
+	{"i1", 5, new(*int), "<value><i1>5</i1></value>"},
+	{"i1/negative", int8(-128), new(*int8), "<value><i1>-128</i1></value>"},
+	{"i2", 300, new(*int), "<value><i2>300</i2></value>"},
+	{"i2/empty", 0, new(*int), "<value><i2/></value>"},
 
 	// string
 	{
@@ -61,9 +116,24 @@ var unmarshalTests = []struct {
 		"<value><base64>T25jZSB1cG9uIGEgdGltZQ==</base64></value>",
 	},
 
+	{
+		"base64/bytes",
+		[]byte("Once upon a time"),
+		new(*[]byte),
+		"<value><base64>T25jZSB1cG9uIGEgdGltZQ==</base64></value>",
+	},
+	{
+		"base64/bytes_wrapped",
+		[]byte("Once upon a time"),
+		new(*[]byte),
+		"<value><base64>T25jZSB1cG9u\nIGEgdGltZQ==</base64></value>",
+	},
+
 	// boolean
 	{"boolean/true", true, new(*bool), "<value><boolean>1</boolean></value>"},
 	{"boolean/false", false, new(*bool), "<value><boolean>0</boolean></value>"},
+	{"boolean/empty", false, new(*bool), "<value><boolean></boolean></value>"},
+	{"boolean/self_closing", false, new(*bool), "<value><boolean/></value>"},
 
 	// double
 	{"double/positive", 12.134, new(*float32), "<value><double>12.134</double></value>"},
@@ -118,6 +188,43 @@ var unmarshalTests = []struct {
 		new(*time.Time),
 		"<value><dateTime.iso8601>2013-12-09T21:00:12+01:00</dateTime.iso8601></value>",
 	},
+	{
+		"datetime/milliseconds",
+		time.Date(2013, 12, 9, 21, 0, 12, 500e6, time.UTC),
+		new(*time.Time),
+		"<value><dateTime.iso8601>20131209T21:00:12.500</dateTime.iso8601></value>",
+	},
+	{
+		"datetime/milliseconds_Z",
+		time.Date(2013, 12, 9, 21, 0, 12, 500e6, time.UTC),
+		new(*time.Time),
+		"<value><dateTime.iso8601>20131209T21:00:12.500Z</dateTime.iso8601></value>",
+	},
+	{
+		"datetime/microseconds_offset",
+		time.Date(2013, 12, 9, 21, 0, 12, 123456e3, time.FixedZone("", 3600)),
+		new(*time.Time),
+		"<value><dateTime.iso8601>20131209T21:00:12.123456+01:00</dateTime.iso8601></value>",
+	},
+	{
+		"datetime/hyphen_microseconds",
+		time.Date(2013, 12, 9, 21, 0, 12, 123456e3, time.UTC),
+		new(*time.Time),
+		"<value><dateTime.iso8601>2013-12-09T21:00:12.123456</dateTime.iso8601></value>",
+	},
+	{
+		"datetime/hyphen_nanoseconds_Z",
+		time.Date(2013, 12, 9, 21, 0, 12, 123456789, time.UTC),
+		new(*time.Time),
+		"<value><dateTime.iso8601>2013-12-09T21:00:12.123456789Z</dateTime.iso8601></value>",
+	},
+
+	{
+		"datetime/bare_tag",
+		testTime(2013, 12, 9, 21, 0, 12, time.UTC),
+		new(*time.Time),
+		"<value><dateTime>20131209T21:00:12</dateTime></value>",
+	},
 
 	// array
 	{
@@ -265,177 +372,1630 @@ func TestTypeMismatchError(t *testing.T) {
 	}
 }
 
-func TestUnmarshalEmptyValueTag(t *testing.T) {
+func TestUnmarshalExponentIntegers(t *testing.T) {
 	t.Parallel()
 
-	var v int
-	if err := unmarshal([]byte("<value/>"), &v); err != nil {
-		t.Fatalf("unmarshal error: %v", err)
+	tests := []struct {
+		name    string
+		xml     string
+		opts    []Option
+		want    int
+		wantErr bool
+	}{
+		{"enabled", "<value><int>1e3</int></value>", []Option{WithExponentIntegers()}, 1000, false},
+		{"enabled_plain", "<value><int>42</int></value>", []Option{WithExponentIntegers()}, 42, false},
+		{"enabled_fraction", "<value><int>1.5e1</int></value>", []Option{WithExponentIntegers()}, 0, true},
+		{"enabled_overflow", "<value><int>1e30</int></value>", []Option{WithExponentIntegers()}, 0, true},
+		{"disabled", "<value><int>1e3</int></value>", nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v int
+			err := Unmarshal([]byte(tt.xml), &v, tt.opts...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %d", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if v != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, v)
+			}
+		})
 	}
 }
 
-func TestUnmarshalEmptyStruct(t *testing.T) {
+func TestUnmarshalStringNumbers(t *testing.T) {
 	t.Parallel()
 
-	const xml = `<value><struct></struct></value>`
-
-	var v any
-	if err := unmarshal([]byte(xml), &v); err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		name    string
+		xml     string
+		target  func() any
+		want    any
+		wantErr bool
+	}{
+		{"int", `<value><string>42</string></value>`, func() any { return new(int) }, 42, false},
+		{"negative_int64", `<value><string> -7 </string></value>`, func() any { return new(int64) }, int64(-7), false},
+		{"float64", `<value><string>3.14</string></value>`, func() any { return new(float64) }, 3.14, false},
+		{"non_numeric", `<value><string>forty-two</string></value>`, func() any { return new(int) }, nil, true},
+		{"fraction_to_int", `<value><string>3.14</string></value>`, func() any { return new(int) }, nil, true},
 	}
-	if v == nil {
-		t.Fatalf("got nil map")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Unmarshal([]byte(tt.xml), tt.target())
+			if _, ok := err.(TypeMismatchError); !ok {
+				t.Fatalf("expected TypeMismatchError without WithStringNumbers, got %T: %v", err, err)
+			}
+
+			target := tt.target()
+			err = Unmarshal([]byte(tt.xml), target, WithStringNumbers())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", reflect.ValueOf(target).Elem())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if got := reflect.ValueOf(target).Elem().Interface(); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
 	}
 }
 
-func TestUnmarshalExistingArray(t *testing.T) {
+func TestUnmarshalStringPreprocessor(t *testing.T) {
 	t.Parallel()
 
-	const xml = `
-<value>
-  <array>
-    <data>
-      <value><int>234</int></value>
-      <value><boolean>1</boolean></value>
-      <value><string>Hello World</string></value>
-      <value><string>Extra Value</string></value>
-    </data>
-  </array>
-</value>`
+	type report struct {
+		Rate   float64 `xmlrpc:"rate"`
+		Amount float64 `xmlrpc:"amount"`
+		Count  int     `xmlrpc:"count"`
+		Label  string  `xmlrpc:"label"`
+	}
 
-	var (
-		v1 int
-		v2 bool
-		v3 string
-		v  = []any{&v1, &v2, &v3}
-	)
+	const xml = `<value><struct>` +
+		`<member><name>rate</name><value><string>12.5%</string></value></member>` +
+		`<member><name>amount</name><value><string>$1,200.00</string></value></member>` +
+		`<member><name>count</name><value><string> 1,024 </string></value></member>` +
+		`<member><name>label</name><value><string>$5%</string></value></member>` +
+		`</struct></value>`
 
-	if err := unmarshal([]byte(xml), &v); err != nil {
-		t.Fatal(err)
+	strip := func(s string) string {
+		return strings.NewReplacer("%", "", "$", "", ",", "").Replace(s)
 	}
 
-	if want := 234; v1 != want {
-		t.Fatalf("v1: want %d, got %d", want, v1)
+	var got report
+	if err := Unmarshal([]byte(xml), &got, WithStringNumbers(), WithStringPreprocessor(strip)); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
 	}
-	if want := true; v2 != want {
-		t.Fatalf("v2: want %t, got %t", want, v2)
-	}
-	if want := "Hello World"; v3 != want {
-		t.Fatalf("v3: want %s, got %s", want, v3)
+	want := report{Rate: 12.5, Amount: 1200, Count: 1024, Label: "$5%"}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
 	}
-	if n := len(v); n != 4 {
-		t.Fatalf("missing appended result, len=%d", n)
+
+	var v float64
+	if err := Unmarshal([]byte(`<value><string>12.5%</string></value>`), &v, WithStringNumbers()); err == nil {
+		t.Fatalf("expected error without preprocessor, got %v", v)
 	}
-	if got, ok := v[3].(string); !ok || got != "Extra Value" {
-		t.Fatalf("v[3]: got %s, want %s", got, "Extra Value")
+	if err := Unmarshal([]byte(`<value><string>12.5</string></value>`), &v, WithStringPreprocessor(strip)); err == nil {
+		t.Fatalf("expected error without WithStringNumbers, got %v", v)
 	}
 }
 
-func TestDecodeNonUTF8Response(t *testing.T) {
-	data, err := os.ReadFile("testdata/fixtures/cp1251.xml")
-	if err != nil {
-		t.Fatal(err)
-	}
+func TestUnmarshalLenientNumbers(t *testing.T) {
+	t.Parallel()
 
-	CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
-		if charset != "cp1251" {
-			return nil, errors.New("unsupported charset: " + charset)
-		}
-		return transform.NewReader(input, charmap.Windows1251.NewDecoder()), nil
+	tests := []struct {
+		name    string
+		xml     string
+		target  func() any
+		want    any
+		wantErr bool
+	}{
+		{"double_to_int", `<value><double>5.0</double></value>`, func() any { return new(int) }, 5, false},
+		{"negative_double_to_int64", `<value><double>-42</double></value>`, func() any { return new(int64) }, int64(-42), false},
+		{"fractional_double_to_int", `<value><double>5.5</double></value>`, func() any { return new(int) }, nil, true},
+		{"overflowing_double_to_int8", `<value><double>300</double></value>`, func() any { return new(int8) }, nil, true},
+		{"huge_double_to_int64", `<value><double>1e20</double></value>`, func() any { return new(int64) }, nil, true},
+		{"int_to_float64", `<value><int>5</int></value>`, func() any { return new(float64) }, 5.0, false},
+		{"i8_to_float32", `<value><i8>-7</i8></value>`, func() any { return new(float32) }, float32(-7), false},
 	}
-	t.Cleanup(func() { CharsetReader = nil })
 
-	var s string
-	if err = unmarshal(data, &s); err != nil {
-		t.Fatalf("unmarshal error: %v", err)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
 
-	expected := "Л.Н. Толстой - Война и Мир"
-	if s != expected {
-		t.Fatalf("unmarshal error:\nexpected: %v\n     got: %v", expected, s)
+			if err := Unmarshal([]byte(tt.xml), tt.target()); err == nil {
+				t.Fatal("expected error without WithLenientNumbers, got nil")
+			}
+
+			target := tt.target()
+			err := Unmarshal([]byte(tt.xml), target, WithLenientNumbers())
+			if tt.wantErr {
+				if _, ok := err.(TypeMismatchError); !ok {
+					t.Fatalf("expected TypeMismatchError, got %T: %v", err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if got := reflect.ValueOf(target).Elem().Interface(); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
 	}
 }
 
-func BenchmarkUnmarshal(b *testing.B) {
-	benchmarks := []struct {
-		name string
-		xml  string
-		ptr  any
+func TestUnmarshalNestedValues(t *testing.T) {
+	t.Parallel()
+
+	deep := strings.Repeat("<value>", 10) + "<int>1</int>" + strings.Repeat("</value>", 10)
+
+	tests := []struct {
+		name    string
+		xml     string
+		opts    []Option
+		want    any
+		wantErr bool
 	}{
-		{"int", "<value><int>12345</int></value>", new(int)},
-		{"string", "<value><string>Hello, World!</string></value>", new(string)},
-		{"bool", "<value><boolean>1</boolean></value>", new(bool)},
-		{
-			"struct",
-			"<value><struct><member><name>Title</name><value><string>Test</string></value></member><member><name>Amount</name><value><int>100</int></value></member></struct></value>",
-			new(book),
-		},
+		{"double_int", "<value><value><int>1</int></value></value>", []Option{WithNestedValues()}, int64(1), false},
+		{"triple_string", "<value> <value><value><string>hi</string></value></value> </value>", []Option{WithNestedValues()}, "hi", false},
+		{"empty", "<value><value></value></value>", []Option{WithNestedValues()}, nil, false},
 		{
-			"array",
-			"<value><array><data><value><int>1</int></value><value><int>2</int></value><value><int>3</int></value></data></array></value>",
-			new([]int),
+			"in_array",
+			"<value><array><data><value><value><int>1</int></value></value><value><int>2</int></value></data></array></value>",
+			[]Option{WithNestedValues()},
+			[]any{int64(1), int64(2)},
+			false,
 		},
+		{"too_deep", deep, []Option{WithNestedValues()}, nil, true},
+		{"strict", "<value><value><int>1</int></value></value>", nil, nil, true},
 	}
 
-	for _, bm := range benchmarks {
-		b.Run(bm.name, func(b *testing.B) {
-			data := []byte(bm.xml)
-			b.ResetTimer()
-			for i := 0; i < b.N; i++ {
-				if err := unmarshal(data, bm.ptr); err != nil {
-					b.Fatal(err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v any
+			err := Unmarshal([]byte(tt.xml), &v, tt.opts...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", v)
 				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if !reflect.DeepEqual(v, tt.want) {
+				t.Fatalf("expected %#v, got %#v", tt.want, v)
 			}
 		})
 	}
 }
 
-func FuzzUnmarshalInt(f *testing.F) {
-	f.Add([]byte(`<value><int>0</int></value>`))
-	f.Add([]byte(`<value><int>123</int></value>`))
-	f.Add([]byte(`<value><int>-456</int></value>`))
-	f.Add([]byte(`<value><i4>789</i4></value>`))
-	f.Add([]byte(`<value><i8>9223372036854775807</i8></value>`))
+func TestUnmarshalMaxDepth(t *testing.T) {
+	t.Parallel()
 
-	f.Fuzz(func(t *testing.T, data []byte) {
-		var result int64
-		_ = unmarshal(data, &result)
-	})
-}
+	nested := func(depth int) []byte {
+		return []byte(strings.Repeat("<value><array><data>", depth-1) + "<value><int>1</int></value>" +
+			strings.Repeat("</data></array></value>", depth-1))
+	}
 
-func FuzzUnmarshalString(f *testing.F) {
-	f.Add([]byte(`<value><string>hello</string></value>`))
-	f.Add([]byte(`<value><string></string></value>`))
-	f.Add([]byte(`<value><string>a &amp; b</string></value>`))
-	f.Add([]byte(`<value>implicit string</value>`))
+	tests := []struct {
+		name    string
+		data    []byte
+		opts    []Option
+		wantErr bool
+	}{
+		{"default_pathological", nested(200000), nil, true},
+		{"default_ok", nested(1000), nil, false},
+		{"custom_at_limit", nested(5), []Option{WithMaxDepth(5)}, false},
+		{"custom_exceeded", nested(6), []Option{WithMaxDepth(5)}, true},
+		{
+			"struct_exceeded",
+			[]byte(strings.Repeat("<value><struct><member><name>a</name>", 6) + "<value/>" +
+				strings.Repeat("</member></struct></value>", 6)),
+			[]Option{WithMaxDepth(5)},
+			true,
+		},
+	}
 
-	f.Fuzz(func(t *testing.T, data []byte) {
-		var result string
-		_ = unmarshal(data, &result)
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v any
+			err := Unmarshal(tt.data, &v, tt.opts...)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "nested deeper") {
+					t.Fatalf("expected depth error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+		})
+	}
 }
 
-func FuzzUnmarshalStruct(f *testing.F) {
-	f.Add([]byte(`<value><struct></struct></value>`))
-	f.Add(
-		[]byte(
-			`<value><struct><member><name>foo</name><value><string>bar</string></value></member></struct></value>`,
-		),
-	)
+func TestUnmarshalOrderedMap(t *testing.T) {
+	t.Parallel()
 
-	f.Fuzz(func(t *testing.T, data []byte) {
-		var result map[string]any
-		_ = unmarshal(data, &result)
-	})
-}
+	const xml = `<value><struct>` +
+		`<member><name>zeta</name><value><int>1</int></value></member>` +
+		`<member><name>alpha</name><value><string>a</string></value></member>` +
+		`<member><name>mid</name><value><boolean>1</boolean></value></member>` +
+		`<member><name>alpha</name><value><string>again</string></value></member>` +
+		`</struct></value>`
 
-func FuzzUnmarshalArray(f *testing.F) {
-	f.Add([]byte(`<value><array><data></data></array></value>`))
-	f.Add([]byte(`<value><array><data><value><int>1</int></value></data></array></value>`))
+	var v OrderedMap
+	if err := Unmarshal([]byte(xml), &v); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
 
-	f.Fuzz(func(t *testing.T, data []byte) {
-		var result []any
-		_ = unmarshal(data, &result)
+	want := OrderedMap{{"zeta", int64(1)}, {"alpha", "a"}, {"mid", true}, {"alpha", "again"}}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("expected %#v, got %#v", want, v)
+	}
+
+	type record struct {
+		Attrs OrderedMap `xmlrpc:"attrs"`
+	}
+	var r record
+	if err := Unmarshal([]byte(`<value><struct><member><name>attrs</name>`+xml+`</member></struct></value>`), &r); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(r.Attrs, want) {
+		t.Fatalf("expected %#v, got %#v", want, r.Attrs)
+	}
+}
+
+func TestUnmarshalValueFactory(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct>` +
+		`<member><name>zeta</name><value><int>1</int></value></member>` +
+		`<member><name>alpha</name><value><struct>` +
+		`<member><name>y</name><value><string>b</string></value></member>` +
+		`<member><name>x</name><value><string>a</string></value></member>` +
+		`</struct></value></member>` +
+		`<member><name>mid</name><value><array><data><value><struct></struct></value></data></array></value></member>` +
+		`</struct></value>`
+
+	ordered := func(typeName string) any {
+		if typeName == "struct" {
+			return new([]Member)
+		}
+		return nil
+	}
+
+	var v any
+	if err := Unmarshal([]byte(xml), &v, WithValueFactory(ordered)); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+
+	want := []Member{
+		{"zeta", int64(1)},
+		{"alpha", []Member{{"y", "b"}, {"x", "a"}}},
+		{"mid", []any{[]Member{}}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("expected %#v, got %#v", want, v)
+	}
+}
+
+func TestUnmarshalValueFactoryTypedMap(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><array><data>` +
+		`<value><struct><member><name>a</name><value><string>1</string></value></member></struct></value>` +
+		`<value><int>2</int></value>` +
+		`</data></array></value>`
+
+	factory := func(typeName string) any {
+		if typeName == "struct" {
+			return new(map[string]string)
+		}
+		return nil
+	}
+
+	var v any
+	if err := Unmarshal([]byte(xml), &v, WithValueFactory(factory)); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	want := []any{map[string]string{"a": "1"}, int64(2)}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("expected %#v, got %#v", want, v)
+	}
+
+	bad := func(string) any { return "not a pointer" }
+	var w any
+	if err := Unmarshal([]byte(xml), &w, WithValueFactory(bad)); err == nil || !strings.Contains(err.Error(), "value factory") {
+		t.Fatalf("expected value factory error, got %v", err)
+	}
+}
+
+func TestUnmarshalEmptyScalarsToAny(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		xml  string
+		want any
+	}{
+		{"boolean", "<value><boolean/></value>", false},
+		{"int", "<value><int></int></value>", int64(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v any
+			if err := unmarshal([]byte(tt.xml), &v); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if v != tt.want {
+				t.Fatalf("expected %#v, got %#v", tt.want, v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalIntegersToAny(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"int", "<value><int>5</int></value>"},
+		{"i4", "<value><i4>5</i4></value>"},
+		{"i8", "<value><i8>5</i8></value>"},
+		{"i1", "<value><i1>5</i1></value>"},
+		{"i2", "<value><i2>300</i2></value>"},
+		{"array", "<value><array><data><value><i8>5</i8></value></data></array></value>"},
+		{"struct", "<value><struct><member><name>n</name><value><i4>5</i4></value></member></struct></value>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v any
+			if err := unmarshal([]byte(tt.xml), &v); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+
+			switch vv := v.(type) {
+			case []any:
+				v = vv[0]
+			case map[string]any:
+				v = vv["n"]
+			}
+			if _, ok := v.(int64); !ok {
+				t.Fatalf("expected int64, got %T", v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSmallIntegerRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"i1", "<value><i1>300</i1></value>"},
+		{"i1/negative", "<value><i1>-129</i1></value>"},
+		{"i2", "<value><i2>40000</i2></value>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var n int
+			if err := unmarshal([]byte(tt.xml), &n); err == nil {
+				t.Fatalf("expected range error, got %d", n)
+			}
+			var v any
+			if err := unmarshal([]byte(tt.xml), &v); err == nil {
+				t.Fatalf("expected range error, got %v", v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalApacheExtensions(t *testing.T) {
+	t.Parallel()
+
+	const ns = `xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions"`
+
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"undeclared_prefix", `<value><struct>` +
+			`<member><name>id</name><value><ex:i8>8589934592</ex:i8></value></member>` +
+			`<member><name>note</name><value><ex:nil/></value></member>` +
+			`<member><name>count</name><value><ex:nil></ex:nil></value></member>` +
+			`<member><name>at</name><value><ex:dateTime.iso8601>20131209T21:00:12</ex:dateTime.iso8601></value></member>` +
+			`</struct></value>`},
+		{"declared_namespace", `<value ` + ns + `><struct>` +
+			`<member><name>id</name><value><ex:i8>8589934592</ex:i8></value></member>` +
+			`<member><name>note</name><value><ex:nil/></value></member>` +
+			`<member><name>count</name><value><ex:nil/></value></member>` +
+			`<member><name>at</name><value><ex:dateTime.iso8601>20131209T21:00:12</ex:dateTime.iso8601></value></member>` +
+			`</struct></value>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			count := 7
+			v := struct {
+				ID    int64     `xmlrpc:"id"`
+				Note  *string   `xmlrpc:"note"`
+				Count *int      `xmlrpc:"count"`
+				At    time.Time `xmlrpc:"at"`
+			}{Note: new(string), Count: &count}
+			if err := unmarshal([]byte(tt.xml), &v); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if v.ID != 8589934592 {
+				t.Errorf("id: expected 8589934592, got %d", v.ID)
+			}
+			if v.Note != nil || v.Count != nil {
+				t.Errorf("expected nil pointers for <ex:nil/>, got note=%v count=%v", v.Note, v.Count)
+			}
+			if want := testTime(2013, 12, 9, 21, 0, 12, time.UTC); !v.At.Equal(want) {
+				t.Errorf("at: expected %v, got %v", want, v.At)
+			}
+
+			var m map[string]any
+			if err := unmarshal([]byte(tt.xml), &m); err != nil {
+				t.Fatalf("unmarshal into map error: %v", err)
+			}
+			if note, ok := m["note"]; !ok || note != nil {
+				t.Errorf("note: expected nil member, got %v (present=%v)", note, ok)
+			}
+			if id, ok := m["id"].(int64); !ok || id != 8589934592 {
+				t.Errorf("id: expected int64 8589934592, got %#v", m["id"])
+			}
+		})
+	}
+}
+
+func TestUnmarshalNil(t *testing.T) {
+	t.Parallel()
+
+	n := 5
+	if err := unmarshal([]byte("<value><nil/></value>"), &n); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected zero value, got %d", n)
+	}
+
+	var v any = "previous"
+	if err := unmarshal([]byte("<value><nil/></value>"), &v); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if v != nil {
+		t.Fatalf("expected nil, got %v", v)
+	}
+}
+
+func TestUnmarshalInvalidBoolean(t *testing.T) {
+	t.Parallel()
+
+	var v bool
+	if err := unmarshal([]byte("<value><boolean>yes</boolean></value>"), &v); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestUnmarshalInvalidBase64(t *testing.T) {
+	t.Parallel()
+
+	var b []byte
+	err := unmarshal([]byte("<value><base64>not base64!</base64></value>"), &b)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "base64") {
+		t.Fatalf("expected base64 error, got: %v", err)
+	}
+}
+
+func TestUnmarshalEmptyValueTag(t *testing.T) {
+	t.Parallel()
+
+	var v int
+	if err := unmarshal([]byte("<value/>"), &v); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+}
+
+func TestUnmarshalEmptyStruct(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct></struct></value>`
+
+	var v any
+	if err := unmarshal([]byte(xml), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v == nil {
+		t.Fatalf("got nil map")
+	}
+}
+
+func TestUnmarshalEmptyStructAsNil(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		xml     string
+		opts    []Option
+		wantNil bool
+		wantLen int
+	}{
+		{"default", `<value><struct></struct></value>`, nil, false, 0},
+		{"enabled", `<value><struct></struct></value>`, []Option{WithEmptyStructAsNil()}, true, 0},
+		{
+			"enabled_non_empty",
+			`<value><struct><member><name>a</name><value><int>1</int></value></member></struct></value>`,
+			[]Option{WithEmptyStructAsNil()},
+			false,
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v any
+			if err := Unmarshal([]byte(tt.xml), &v, tt.opts...); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if tt.wantNil {
+				if v != nil {
+					t.Fatalf("expected nil, got %#v", v)
+				}
+			} else if m, ok := v.(map[string]any); !ok || m == nil || len(m) != tt.wantLen {
+				t.Fatalf("expected map with %d members, got %#v", tt.wantLen, v)
+			}
+
+			var m map[string]int
+			if err := Unmarshal([]byte(tt.xml), &m, tt.opts...); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if (m == nil) != tt.wantNil || len(m) != tt.wantLen {
+				t.Fatalf("map: expected nil=%t len=%d, got %#v", tt.wantNil, tt.wantLen, m)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDisallowUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct>` +
+		`<member><name>name</name><value><string>John</string></value></member>` +
+		`<member><name>email</name><value><string>john@example.com</string></value></member>` +
+		`</struct></value>`
+
+	type user struct {
+		Name string `xmlrpc:"name"`
+	}
+
+	var u user
+	if err := Unmarshal([]byte(xml), &u); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+
+	for _, opt := range []Option{WithDisallowUnknownFields(), WithStrict()} {
+		err := Unmarshal([]byte(xml), &u, opt)
+		if err == nil || !strings.Contains(err.Error(), `"email"`) {
+			t.Fatalf("expected error naming member \"email\", got %v", err)
+		}
+	}
+
+	var m map[string]string
+	if err := Unmarshal([]byte(xml), &m, WithDisallowUnknownFields()); err != nil {
+		t.Fatalf("Unmarshal into map error: %v", err)
+	}
+	if len(m) != 2 {
+		t.Fatalf("expected 2 members, got %#v", m)
+	}
+}
+
+func TestUnmarshalCaseInsensitiveFields(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct>` +
+		`<member><name>UserName</name><value><string>john</string></value></member>` +
+		`<member><name>EMAIL</name><value><string>john@example.com</string></value></member>` +
+		`<member><name>CODE</name><value><string>X</string></value></member>` +
+		`</struct></value>`
+
+	type user struct {
+		UserName string `xmlrpc:"username"`
+		Email    string `xmlrpc:"email"`
+		Code     string `xmlrpc:"code"`
+		CODE     string `xmlrpc:"CODE"`
+	}
+
+	var u user
+	if err := Unmarshal([]byte(xml), &u); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if u != (user{CODE: "X"}) {
+		t.Fatalf("expected only exact matches by default, got %+v", u)
+	}
+
+	u = user{}
+	if err := Unmarshal([]byte(xml), &u, WithCaseInsensitiveFields()); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	// "CODE" also matches "code" ignoring case, but the exact match wins.
+	expected := user{UserName: "john", Email: "john@example.com", CODE: "X"}
+	if u != expected {
+		t.Fatalf("expected %+v, got %+v", expected, u)
+	}
+}
+
+func TestUnmarshalTypedMap(t *testing.T) {
+	t.Parallel()
+
+	const onlyStrings = `<value><struct>
+  <member><name>name</name><value><string>John</string></value></member>
+  <member><name>city</name><value>Zurich</value></member>
+</struct></value>`
+
+	var m map[string]string
+	if err := unmarshal([]byte(onlyStrings), &m); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	want := map[string]string{"name": "John", "city": "Zurich"}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("expected %v, got %v", want, m)
+	}
+
+	const mixed = `<value><struct>
+  <member><name>name</name><value><string>John</string></value></member>
+  <member><name>age</name><value><int>42</int></value></member>
+</struct></value>`
+
+	err := unmarshal([]byte(mixed), &m)
+	if _, ok := err.(TypeMismatchError); !ok {
+		t.Fatalf("expected TypeMismatchError, got %T: %v", err, err)
+	}
+}
+
+func TestUnmarshalFixedArray(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		xml      string
+		opts     []Option
+		expected [3]int
+		wantErr  bool
+	}{
+		{
+			"exact",
+			`<value><array><data><value><int>1</int></value><value><int>2</int></value><value><int>3</int></value></data></array></value>`,
+			nil, [3]int{1, 2, 3}, false,
+		},
+		{
+			"too_few",
+			`<value><array><data><value><int>1</int></value><value><int>2</int></value></data></array></value>`,
+			nil, [3]int{}, true,
+		},
+		{
+			"too_many",
+			`<value><array><data><value><int>1</int></value><value><int>2</int></value><value><int>3</int></value><value><int>4</int></value></data></array></value>`,
+			nil, [3]int{}, true,
+		},
+		{
+			"too_few_truncated",
+			`<value><array><data><value><int>1</int></value><value><int>2</int></value></data></array></value>`,
+			[]Option{WithTruncateArrays()}, [3]int{1, 2, 0}, false,
+		},
+		{
+			"too_many_truncated",
+			`<value><array><data><value><int>1</int></value><value><int>2</int></value><value><int>3</int></value><value><int>4</int></value></data></array></value>`,
+			[]Option{WithTruncateArrays()}, [3]int{1, 2, 3}, false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := [3]int{9, 9, 9}
+			err := Unmarshal([]byte(tt.xml), &v, tt.opts...)
+			if tt.wantErr {
+				if _, ok := err.(TypeMismatchError); !ok {
+					t.Fatalf("expected TypeMismatchError, got %T: %v", err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if v != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDuplicateMembers(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct>` +
+		`<member><name>name</name><value><string>first</string></value></member>` +
+		`<member><name>tags</name><value><array><data><value><string>a</string></value></data></array></value></member>` +
+		`<member><name>name</name><value><string>last</string></value></member>` +
+		`<member><name>tags</name><value><array><data><value><string>b</string></value></data></array></value></member>` +
+		`</struct></value>`
+
+	type item struct {
+		Name string   `xmlrpc:"name"`
+		Tags []string `xmlrpc:"tags"`
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want item
+	}{
+		{"last_wins", nil, item{Name: "last", Tags: []string{"b"}}},
+		{"first_wins", []Option{WithFirstMemberWins()}, item{Name: "first", Tags: []string{"a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v item
+			if err := Unmarshal([]byte(xml), &v, tt.opts...); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if !reflect.DeepEqual(v, tt.want) {
+				t.Fatalf("struct: expected %+v, got %+v", tt.want, v)
+			}
+
+			var m map[string]any
+			if err := Unmarshal([]byte(xml), &m, tt.opts...); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if m["name"] != tt.want.Name {
+				t.Fatalf("map: expected name %q, got %#v", tt.want.Name, m["name"])
+			}
+		})
+	}
+}
+
+func TestUnmarshalURLValues(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct>
+  <member><name>tag</name><value><array><data><value><string>a</string></value><value><string>b</string></value></data></array></value></member>
+  <member><name>name</name><value><string>John</string></value></member>
+  <member><name>city</name><value>Zurich</value></member>
+</struct></value>`
+
+	var v url.Values
+	if err := unmarshal([]byte(xml), &v); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	want := url.Values{
+		"tag":  {"a", "b"},
+		"name": {"John"},
+		"city": {"Zurich"},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("expected %v, got %v", want, v)
+	}
+}
+
+func TestUnmarshalScalarIntoSlice(t *testing.T) {
+	t.Parallel()
+
+	// Scalars are only wrapped for map members, not for slice targets.
+	var v []string
+	err := unmarshal([]byte("<value><string>a</string></value>"), &v)
+	if _, ok := err.(TypeMismatchError); !ok {
+		t.Fatalf("expected TypeMismatchError, got %T: %v", err, err)
+	}
+}
+
+func TestUnmarshalExistingArray(t *testing.T) {
+	t.Parallel()
+
+	const xml = `
+<value>
+  <array>
+    <data>
+      <value><int>234</int></value>
+      <value><boolean>1</boolean></value>
+      <value><string>Hello World</string></value>
+      <value><string>Extra Value</string></value>
+    </data>
+  </array>
+</value>`
+
+	var (
+		v1 int
+		v2 bool
+		v3 string
+		v  = []any{&v1, &v2, &v3}
+	)
+
+	if err := unmarshal([]byte(xml), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 234; v1 != want {
+		t.Fatalf("v1: want %d, got %d", want, v1)
+	}
+	if want := true; v2 != want {
+		t.Fatalf("v2: want %t, got %t", want, v2)
+	}
+	if want := "Hello World"; v3 != want {
+		t.Fatalf("v3: want %s, got %s", want, v3)
+	}
+	if n := len(v); n != 4 {
+		t.Fatalf("missing appended result, len=%d", n)
+	}
+	if got, ok := v[3].(string); !ok || got != "Extra Value" {
+		t.Fatalf("v[3]: got %s, want %s", got, "Extra Value")
+	}
+}
+
+func TestUnmarshalPublic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{"value", `<value><string>hello</string></value>`},
+		{
+			"method_response",
+			`<?xml version="1.0"?><methodResponse><params><param><value><string>hello</string></value></param></params></methodResponse>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var s string
+			if err := Unmarshal([]byte(tt.xml), &s); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if s != "hello" {
+				t.Fatalf("expected 'hello', got %q", s)
+			}
+		})
+	}
+}
+
+func TestUnmarshalCommentsAndWhitespace(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<?xml version="1.0"?>
+<!-- generated by server -->
+<methodResponse>
+  <!-- result follows -->
+  <params>
+    <param>
+      <value>
+        <!-- a struct -->
+        <struct>
+          <!-- first member -->
+          <member>
+            <name>ids</name>
+            <?server-hint compact?>
+            <value>
+              <array>
+                <!-- elements -->
+                <data>
+                  <value><int>1</int></value>
+                  <!-- skipped: 2 -->
+                  <value><int><!-- three -->3</int></value>
+                  <?pi?>
+                </data>
+              </array>
+            </value>
+          </member>
+          <member>
+            <!-- second member -->
+            <name>title</name>
+            <value><string>split <!-- by a comment -->text<![CDATA[ & cdata]]></string></value>
+          </member>
+        </struct>
+      </value>
+    </param>
+  </params>
+</methodResponse>
+<!-- trailing comment -->
+`
+
+	type result struct {
+		IDs   []int  `xmlrpc:"ids"`
+		Title string `xmlrpc:"title"`
+	}
+	want := result{IDs: []int{1, 3}, Title: "split text & cdata"}
+
+	var got result
+	if err := Unmarshal([]byte(xml), &got); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+
+	var v any
+	if err := Unmarshal([]byte(xml), &v); err != nil {
+		t.Fatalf("Unmarshal into any error: %v", err)
+	}
+	wantAny := map[string]any{
+		"ids":   []any{int64(1), int64(3)},
+		"title": "split text & cdata",
+	}
+	if !reflect.DeepEqual(v, wantAny) {
+		t.Errorf("Unmarshal() into any = %#v, want %#v", v, wantAny)
+	}
+
+	var s string
+	if err := Unmarshal([]byte("<value>\n  bare<!-- comment --> string\n</value>"), &s); err != nil {
+		t.Fatalf("Unmarshal untyped value error: %v", err)
+	}
+	if s != "bare string" {
+		t.Errorf("Unmarshal() untyped value = %q, want %q", s, "bare string")
+	}
+}
+
+func TestUnmarshalPublicFault(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>Too many parameters.</string></value></member></struct></value></fault></methodResponse>`
+
+	var s string
+	err := Unmarshal([]byte(xml), &s)
+
+	var fault FaultError
+	if !errors.As(err, &fault) {
+		t.Fatalf("expected FaultError, got %T: %v", err, err)
+	}
+	if fault.Code != 4 {
+		t.Fatalf("expected fault code 4, got %d", fault.Code)
+	}
+}
+
+func TestUnmarshalResponseFaultText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		xml  string
+		want string
+	}{
+		{
+			"escaped",
+			`<methodResponse><params><param><value><string>foo&lt;fault&gt;bar</string></value></param></params></methodResponse>`,
+			"foo<fault>bar",
+		},
+		{
+			"cdata",
+			`<methodResponse><params><param><value><string><![CDATA[<fault><value>x</value></fault>]]></string></value></param></params></methodResponse>`,
+			"<fault><value>x</value></fault>",
+		},
+		{
+			"comment",
+			`<methodResponse><!-- <fault> --><params><param><value><string>ok</string></value></param></params></methodResponse>`,
+			"ok",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var s string
+			if err := Unmarshal([]byte(tt.xml), &s); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if s != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, s)
+			}
+		})
+	}
+}
+
+func TestUnmarshalResponseUnexpectedElement(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<methodResponse><result><fault><value><struct></struct></value></fault></result></methodResponse>`
+
+	var s string
+	err := Unmarshal([]byte(xml), &s)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	var fault FaultError
+	if errors.As(err, &fault) {
+		t.Fatalf("expected non-fault error, got %v", err)
+	}
+}
+
+func TestUnmarshalFaultCodeTypes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		code    string
+		want    int64
+		wantErr bool
+	}{
+		{"int", `<int>4</int>`, 4, false},
+		{"i4", `<i4>-32601</i4>`, -32601, false},
+		{"i8", `<i8>8589934592</i8>`, 8589934592, false},
+		{"string", `<string>42</string>`, 42, false},
+		{"string_whitespace", `<string> -7 </string>`, -7, false},
+		{"string_not_numeric", `<string>oops</string>`, 0, true},
+		{"double", `<double>1.5</double>`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			xml := `<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value>` + tt.code + `</value></member>` +
+				`<member><name>faultString</name><value><string>failed</string></value></member>` +
+				`</struct></value></fault></methodResponse>`
+
+			var s string
+			err := Unmarshal([]byte(xml), &s)
+
+			var fault FaultError
+			if tt.wantErr {
+				if err == nil || errors.As(err, &fault) {
+					t.Fatalf("expected parse error, got %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &fault) {
+				t.Fatalf("expected FaultError, got %T: %v", err, err)
+			}
+			if got := fault.Code64(); got != tt.want {
+				t.Errorf("Code64() = %d, want %d", got, tt.want)
+			}
+			if fault.Code != int(tt.want) {
+				t.Errorf("Code = %d, want %d", fault.Code, int(tt.want))
+			}
+			if fault.String != "failed" {
+				t.Errorf("String = %q, want %q", fault.String, "failed")
+			}
+		})
+	}
+}
+
+func TestUnmarshalResponseRoot(t *testing.T) {
+	t.Parallel()
+
+	const envelope = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Header><methodResponse><params><param><value><string>decoy</string></value></param></params></methodResponse></soap:Header>
+  <soap:Body>
+    <methodResponse><params><param><value><string>hello</string></value></param></params></methodResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"path", "Envelope/Body/methodResponse", "hello", false},
+		{"slashes", "/Envelope/Body/methodResponse/", "hello", false},
+		{"header", "Envelope/Header/methodResponse", "decoy", false},
+		{"missing", "Envelope/Body/other", "", true},
+		{"wrong_root", "Body/methodResponse", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var s string
+			err := Unmarshal([]byte(envelope), &s, WithResponseRoot(tt.path))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if s != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, s)
+			}
+		})
+	}
+}
+
+func TestUnmarshalResponseRootFault(t *testing.T) {
+	t.Parallel()
+
+	const envelope = `<Envelope><Body><methodResponse><fault><value><struct>` +
+		`<member><name>faultCode</name><value><int>4</int></value></member>` +
+		`<member><name>faultString</name><value><string>Too many parameters.</string></value></member>` +
+		`</struct></value></fault></methodResponse></Body></Envelope>`
+
+	var s string
+	err := Unmarshal([]byte(envelope), &s, WithResponseRoot("Envelope/Body/methodResponse"))
+
+	var fault FaultError
+	if !errors.As(err, &fault) {
+		t.Fatalf("expected FaultError, got %T: %v", err, err)
+	}
+	if fault.Code != 4 {
+		t.Fatalf("expected fault code 4, got %d", fault.Code)
+	}
+}
+
+func TestUnmarshalPublicNonPointer(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><string>hello</string></value>`
+
+	var s string
+	if err := Unmarshal([]byte(xml), s); err == nil {
+		t.Fatal("expected error for non-pointer, got nil")
+	}
+	if err := Unmarshal([]byte(xml), (*string)(nil)); err == nil {
+		t.Fatal("expected error for nil pointer, got nil")
+	}
+	if err := Unmarshal([]byte(xml), nil); err == nil {
+		t.Fatal("expected error for nil, got nil")
+	}
+}
+
+func TestUnmarshalThousandsSeparators(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		xml     string
+		opts    []Option
+		want    int
+		wantErr bool
+	}{
+		{"enabled", "<value><int>1,000</int></value>", []Option{WithThousandsSeparators()}, 1000, false},
+		{"enabled_negative", "<value><i4>-1,234,567</i4></value>", []Option{WithThousandsSeparators()}, -1234567, false},
+		{"enabled_plain", "<value><int>1000</int></value>", []Option{WithThousandsSeparators()}, 1000, false},
+		{"enabled_ambiguous", "<value><int>1,00,000</int></value>", []Option{WithThousandsSeparators()}, 0, true},
+		{"disabled", "<value><int>1,000</int></value>", nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v int
+			err := Unmarshal([]byte(tt.xml), &v, tt.opts...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %d", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if v != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalUnmarshaler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		xml     string
+		wantID  string
+		wantRaw string
+	}{
+		{"string", "<value><string>42</string></value>", "42", "<string>42</string>"},
+		{"int", "<value><int>42</int></value>", "42", "<int>42</int>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v flexibleID
+			if err := unmarshal([]byte(tt.xml), &v); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if v.ID != tt.wantID {
+				t.Errorf("ID: expected %q, got %q", tt.wantID, v.ID)
+			}
+			if v.Raw != tt.wantRaw {
+				t.Errorf("Raw: expected %q, got %q", tt.wantRaw, v.Raw)
+			}
+		})
+	}
+}
+
+func TestUnmarshalUnmarshalerInStruct(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct><member><name>id</name><value><int>7</int></value></member><member><name>name</name><value><string>seven</string></value></member></struct></value>`
+
+	var v struct {
+		ID   flexibleID `xmlrpc:"id"`
+		Name string     `xmlrpc:"name"`
+	}
+	if err := unmarshal([]byte(xml), &v); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if v.ID.ID != "7" || v.Name != "seven" {
+		t.Fatalf("unexpected result: %+v", v)
+	}
+}
+
+func TestUnmarshalTextUnmarshaler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		xml  string
+		want priority
+	}{
+		{"string", "<value><string>high</string></value>", priorityHigh},
+		{"untyped", "<value>low</value>", priorityLow},
+		{"int", "<value><int> 2 </int></value>", priorityHigh},
+		{"i4", "<value><i4>1</i4></value>", priorityLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var p priority
+			if err := unmarshal([]byte(tt.xml), &p); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if p != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, p)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTextUnmarshalerInStruct(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct>` +
+		`<member><name>ip</name><value><string>192.0.2.1</string></value></member>` +
+		`<member><name>priority</name><value><string>high</string></value></member>` +
+		`<member><name>history</name><value><array><data><value><string>low</string></value><value><int>2</int></value></data></array></value></member>` +
+		`</struct></value>`
+
+	var v struct {
+		IP       net.IP     `xmlrpc:"ip"`
+		Priority *priority  `xmlrpc:"priority"`
+		History  []priority `xmlrpc:"history"`
+	}
+	if err := unmarshal([]byte(xml), &v); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if !v.IP.Equal(net.IPv4(192, 0, 2, 1)) {
+		t.Errorf("IP: expected 192.0.2.1, got %v", v.IP)
+	}
+	if v.Priority == nil || *v.Priority != priorityHigh {
+		t.Errorf("Priority: expected %d, got %v", priorityHigh, v.Priority)
+	}
+	if want := []priority{priorityLow, priorityHigh}; !reflect.DeepEqual(v.History, want) {
+		t.Errorf("History: expected %v, got %v", want, v.History)
+	}
+}
+
+func TestUnmarshalTextUnmarshalerError(t *testing.T) {
+	t.Parallel()
+
+	var p priority
+	err := unmarshal([]byte("<value><string>urgent</string></value>"), &p)
+	if want := `unknown priority "urgent"`; err == nil || err.Error() != want {
+		t.Fatalf("expected error %q, got %v", want, err)
+	}
+
+	var ip net.IP
+	if err := unmarshal([]byte("<value><string>not-an-ip</string></value>"), &ip); err == nil {
+		t.Fatal("expected error for invalid IP, got nil")
+	}
+}
+
+func TestBindMembers(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<methodResponse><params><param><value><struct>` +
+		`<member><name>user</name><value><struct><member><name>Title</name><value><string>kolo</string></value></member></struct></value></member>` +
+		`<member><name>ignored</name><value><array><data><value><int>1</int></value></data></array></value></member>` +
+		`<member><name>count</name><value><int>42</int></value></member>` +
+		`</struct></value></param></params></methodResponse>`
+
+	var u book
+	var n int
+	if err := BindMembers([]byte(xml), map[string]any{"user": &u, "count": &n}); err != nil {
+		t.Fatalf("BindMembers error: %v", err)
+	}
+	if u.Title != "kolo" {
+		t.Errorf("user: expected title 'kolo', got %+v", u)
+	}
+	if n != 42 {
+		t.Errorf("count: expected 42, got %d", n)
+	}
+
+	missing := "unchanged"
+	if err := BindMembers([]byte(xml), map[string]any{"missing": &missing}); err != nil {
+		t.Fatalf("BindMembers error: %v", err)
+	}
+	if missing != "unchanged" {
+		t.Errorf("missing: expected 'unchanged', got %q", missing)
+	}
+}
+
+func TestBindMembersErrors(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><struct><member><name>count</name><value><string>many</string></value></member></struct></value>`
+
+	var n int
+	err := BindMembers([]byte(xml), map[string]any{"count": &n})
+	var mismatch TypeMismatchError
+	if !errors.As(err, &mismatch) || !strings.Contains(err.Error(), `member "count"`) {
+		t.Fatalf("expected type mismatch naming member count, got %v", err)
+	}
+
+	if err := BindMembers([]byte(xml), map[string]any{"count": n}); err == nil {
+		t.Fatal("expected error for non-pointer binding, got nil")
+	}
+
+	if err := BindMembers([]byte(`<value><int>1</int></value>`), map[string]any{"count": &n}); err == nil {
+		t.Fatal("expected error for non-struct value, got nil")
+	}
+
+	const fault = `<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>nope</string></value></member></struct></value></fault></methodResponse>`
+	var f FaultError
+	if err := BindMembers([]byte(fault), map[string]any{"count": &n}); !errors.As(err, &f) || f.Code != 4 {
+		t.Fatalf("expected fault 4, got %v", err)
+	}
+}
+
+func TestUnmarshalDateTimeToString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		xml      string
+		want     string
+		wantTime bool
+	}{
+		{"valid", "<value><dateTime.iso8601>20131209T21:00:12</dateTime.iso8601></value>", "20131209T21:00:12", true},
+		{"no_suffix", "<value><dateTime>20131209T21:00:12</dateTime></value>", "20131209T21:00:12", true},
+		{"malformed", "<value><dateTime.iso8601>2013-12-09 9pm</dateTime.iso8601></value>", "2013-12-09 9pm", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var s string
+			if err := unmarshal([]byte(tt.xml), &s); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if s != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, s)
+			}
+
+			var ts time.Time
+			err := unmarshal([]byte(tt.xml), &ts)
+			if tt.wantTime {
+				if err != nil {
+					t.Fatalf("unmarshal into time.Time error: %v", err)
+				}
+				if want := testTime(2013, 12, 9, 21, 0, 12, time.UTC); !ts.Equal(want) {
+					t.Fatalf("expected %v, got %v", want, ts)
+				}
+			} else if err == nil {
+				t.Fatalf("expected error decoding %q into time.Time, got %v", tt.want, ts)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDateTimeLayouts(t *testing.T) {
+	t.Parallel()
+
+	const xml = "<value><dateTime.iso8601>2013/12/09 21:00:12</dateTime.iso8601></value>"
+
+	var ts time.Time
+	if err := Unmarshal([]byte(xml), &ts); err == nil {
+		t.Fatal("expected error without custom layout, got nil")
+	}
+
+	if err := Unmarshal([]byte(xml), &ts, WithDateTimeLayouts("2006-01-02", "2006/01/02 15:04:05")); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if want := testTime(2013, 12, 9, 21, 0, 12, time.UTC); !ts.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, ts)
+	}
+
+	// Built-in layouts are tried first.
+	var v any
+	if err := Unmarshal([]byte("<value><dateTime.iso8601>20131209T21:00:12</dateTime.iso8601></value>"), &v,
+		WithDateTimeLayouts("20060102T04:15:05")); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if want := testTime(2013, 12, 9, 21, 0, 12, time.UTC); !v.(time.Time).Equal(want) {
+		t.Fatalf("expected %v, got %v", want, v)
+	}
+}
+
+func TestUnmarshalEmptyValueInArray(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<value><array><data><value></value><value><int>1</int></value></data></array></value>`
+
+	var v []any
+	if err := unmarshal([]byte(xml), &v); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if len(v) != 2 || v[0] != nil || v[1] != int64(1) {
+		t.Fatalf("unexpected result: %#v", v)
+	}
+}
+
+func TestDecodeNonUTF8Response(t *testing.T) {
+	data, err := os.ReadFile("testdata/fixtures/cp1251.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if charset != "cp1251" {
+			return nil, errors.New("unsupported charset: " + charset)
+		}
+		return transform.NewReader(input, charmap.Windows1251.NewDecoder()), nil
+	}
+	t.Cleanup(func() { CharsetReader = nil })
+
+	var s string
+	if err = unmarshal(data, &s); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	expected := "Л.Н. Толстой - Война и Мир"
+	if s != expected {
+		t.Fatalf("unmarshal error:\nexpected: %v\n     got: %v", expected, s)
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		xml  string
+		ptr  any
+	}{
+		{"int", "<value><int>12345</int></value>", new(int)},
+		{"string", "<value><string>Hello, World!</string></value>", new(string)},
+		{"bool", "<value><boolean>1</boolean></value>", new(bool)},
+		{
+			"struct",
+			"<value><struct><member><name>Title</name><value><string>Test</string></value></member><member><name>Amount</name><value><int>100</int></value></member></struct></value>",
+			new(book),
+		},
+		{
+			"array",
+			"<value><array><data><value><int>1</int></value><value><int>2</int></value><value><int>3</int></value></data></array></value>",
+			new([]int),
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			data := []byte(bm.xml)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := unmarshal(data, bm.ptr); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func FuzzUnmarshalInt(f *testing.F) {
+	f.Add([]byte(`<value><int>0</int></value>`))
+	f.Add([]byte(`<value><int>123</int></value>`))
+	f.Add([]byte(`<value><int>-456</int></value>`))
+	f.Add([]byte(`<value><i4>789</i4></value>`))
+	f.Add([]byte(`<value><i8>9223372036854775807</i8></value>`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var result int64
+		_ = unmarshal(data, &result)
+	})
+}
+
+func FuzzUnmarshalString(f *testing.F) {
+	f.Add([]byte(`<value><string>hello</string></value>`))
+	f.Add([]byte(`<value><string></string></value>`))
+	f.Add([]byte(`<value><string>a &amp; b</string></value>`))
+	f.Add([]byte(`<value>implicit string</value>`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var result string
+		_ = unmarshal(data, &result)
+	})
+}
+
+func FuzzUnmarshalStruct(f *testing.F) {
+	f.Add([]byte(`<value><struct></struct></value>`))
+	f.Add(
+		[]byte(
+			`<value><struct><member><name>foo</name><value><string>bar</string></value></member></struct></value>`,
+		),
+	)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var result map[string]any
+		_ = unmarshal(data, &result)
+	})
+}
+
+func FuzzUnmarshalArray(f *testing.F) {
+	f.Add([]byte(`<value><array><data></data></array></value>`))
+	f.Add([]byte(`<value><array><data><value><int>1</int></value></data></array></value>`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var result []any
+		_ = unmarshal(data, &result)
 	})
 }
 
@@ -452,3 +2012,46 @@ func FuzzUnmarshalAny(f *testing.F) {
 		_ = unmarshal(data, &result)
 	})
 }
+
+func BenchmarkUnmarshalResponseLargeArray(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0"?><methodResponse><params><param><value><array><data>`)
+	for i := range 10000 {
+		fmt.Fprintf(&sb, "<value><struct><member><name>id</name><value><int>%d</int></value></member>"+
+			"<member><name>name</name><value><string>item %d</string></value></member></struct></value>", i, i)
+	}
+	sb.WriteString(`</data></array></value></param></params></methodResponse>`)
+	data := sb.String()
+
+	type item struct {
+		ID   int    `xmlrpc:"id"`
+		Name string `xmlrpc:"name"`
+	}
+
+	// buffered reads the whole body before decoding, as the client used to.
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			body, err := io.ReadAll(iotest.HalfReader(strings.NewReader(data)))
+			if err != nil {
+				b.Fatal(err)
+			}
+			var v []item
+			if err := Unmarshal(body, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// streaming decodes the body while it is read, as the client does.
+	b.Run("streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v []item
+			dec := newDecoder(iotest.HalfReader(strings.NewReader(data)), decodeOptions{})
+			if err := dec.unmarshalResponse(&v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,135 +2,540 @@ package xmlrpc
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
 	"reflect"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Base64 is a string type that will be encoded as base64 in XML-RPC requests.
 type Base64 string
 
+// Member is a single member of an XML-RPC struct.
+type Member struct {
+	Name  string
+	Value any
+}
+
+// OrderedMap is a map-like list of struct members that is encoded as an
+// XML-RPC struct with the members in slice order, unlike maps, whose keys
+// are sorted. Structs can also be decoded into an OrderedMap to keep the
+// member order of the document; duplicate members are kept as well.
+type OrderedMap []Member
+
+// StructValuer is implemented by struct types that declare their XML-RPC
+// struct members explicitly, e.g. because their fields are unexported.
+// The members are encoded in the order returned, instead of the fields
+// of the type.
+type StructValuer interface {
+	XMLRPCStructMembers() []Member
+}
+
+// encodeOptions holds configuration for encoding XML-RPC values.
+type encodeOptions struct {
+	// timeZoneOffset appends the zone offset to encoded times.
+	timeZoneOffset bool
+	// timeLocation is the location times are converted to before encoding.
+	timeLocation *time.Location
+	// canonical guarantees a single serialization for every value.
+	canonical bool
+	// jsonTags falls back to json struct tags for fields without an xmlrpc tag.
+	jsonTags bool
+}
+
+// WithTimeZoneOffset makes the client encode [time.Time] values with their
+// zone offset, e.g. "20131209T21:00:12+01:00". By default times are encoded
+// without zone information. Unless [WithTimeLocation] is also given, times
+// keep their own location instead of being converted to UTC.
+func WithTimeZoneOffset() Option {
+	return func(o *clientOptions) {
+		o.encode.timeZoneOffset = true
+	}
+}
+
+// WithTimeLocation makes the client convert [time.Time] values to loc before
+// encoding them. By default times are converted to UTC, so the same instant
+// always produces the same value on the wire.
+func WithTimeLocation(loc *time.Location) Option {
+	return func(o *clientOptions) {
+		o.encode.timeLocation = loc
+	}
+}
+
+// Canonical makes the client encode requests in a canonical form, so equal
+// arguments always produce byte-identical request bodies, e.g. for hashing or
+// signing with [WithRequestSigner]. Canonical output has no whitespace between
+// elements, map keys in sorted order, struct members in field order, nil
+// values as <value/>, and empty strings, arrays and structs as a start and end
+// tag pair. In addition to the default encoding:
+//
+//   - all integers within the 32-bit range use <int>, including unsigned ones,
+//     which are encoded as <i4> by default;
+//   - negative zero is encoded as 0;
+//   - NaN and infinite floats are rejected, as they have no XML-RPC form.
+func Canonical() Option {
+	return func(o *clientOptions) {
+		o.encode.canonical = true
+	}
+}
+
+// WithJSONTags makes the client read the `json` tag of struct fields that
+// have no `xmlrpc` tag, both when encoding and decoding, so structs annotated
+// for encoding/json need no second set of tags. The name, "-" and the
+// omitempty option of json tags are honored; other json options are ignored.
+// An `xmlrpc` tag always takes precedence over the `json` tag.
+func WithJSONTags() Option {
+	return func(o *clientOptions) {
+		o.encode.jsonTags = true
+		o.decode.jsonTags = true
+	}
+}
+
+type encoder struct {
+	opts encodeOptions
+}
+
+// Encoder writes XML-RPC <value> elements to an output stream, reusing its
+// internal buffer across calls to [Encoder.Encode].
+type Encoder struct {
+	w   io.Writer
+	enc encoder
+	buf bytes.Buffer
+}
+
+// NewEncoder returns a new [Encoder] that writes to w.
+//
+// Encoding options such as [Canonical] may be passed in opts; options
+// unrelated to encoding are ignored.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	options := &clientOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return &Encoder{w: w, enc: encoder{opts: options.encode}}
+}
+
+// Encode writes the <value> element of v to the stream. The value is encoded
+// in full before it is written, so nothing is written if encoding fails.
+func (e *Encoder) Encode(v any) error {
+	e.buf.Reset()
+	if err := e.enc.encode(&e.buf, v); err != nil {
+		return err
+	}
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
 func marshal(v any) ([]byte, error) {
-	if v == nil {
-		return []byte{}, nil
+	return (&encoder{}).marshal(v)
+}
+
+// bufferPool holds the buffers values are encoded into, so repeated encoding
+// reuses their memory.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// maxPooledBuffer is the capacity above which buffers are not returned to
+// bufferPool, so a single large value does not pin its memory.
+const maxPooledBuffer = 64 << 10
+
+// getBuffer returns an empty buffer from bufferPool.
+func getBuffer() *bytes.Buffer {
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	return b
+}
+
+// putBuffer returns b to bufferPool. b must not be used afterwards.
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() <= maxPooledBuffer {
+		bufferPool.Put(b)
 	}
+}
+
+func (enc *encoder) marshal(v any) ([]byte, error) {
+	b := getBuffer()
+	defer putBuffer(b)
 
-	val := reflect.ValueOf(v)
-	return encodeValue(val)
+	if err := enc.encode(b, v); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(b.Bytes()), nil
 }
 
-func encodeValue(val reflect.Value) ([]byte, error) {
-	var b []byte
-	var err error
+// encode writes the <value> element of v to b. On error, b may hold a
+// partially written value.
+func (enc *encoder) encode(b *bytes.Buffer, v any) error {
+	if v == nil {
+		b.WriteString("<value/>")
+		return nil
+	}
+
+	return enc.encodeValue(b, reflect.ValueOf(v))
+}
 
-	if val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+func (enc *encoder) encodeValue(b *bytes.Buffer, val reflect.Value) error {
+	// Follow pointers and interfaces to the value they ultimately refer to,
+	// e.g. a **int or an any holding a *time.Time.
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
 		if val.IsNil() {
-			return []byte("<value/>"), nil
+			b.WriteString("<value/>")
+			return nil
 		}
 
 		val = val.Elem()
 	}
 
+	if val.Type() == reflect.TypeFor[big.Int]() {
+		encodeBigInt(b, val)
+		return nil
+	}
+
+	if tm, ok := textMarshaler(val); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return fmt.Errorf("xmlrpc: failed to marshal %s: %w", val.Type(), err)
+		}
+		b.WriteString("<value><string>")
+		xml.Escape(b, text)
+		b.WriteString("</string></value>")
+		return nil
+	}
+
+	b.WriteString("<value>")
+
 	switch val.Kind() {
 	case reflect.Struct:
 		if t, ok := val.Interface().(time.Time); ok {
-			b = fmt.Appendf(nil, "<dateTime.iso8601>%s</dateTime.iso8601>", t.Format(iso8601))
-		} else {
-			b, err = encodeStruct(val)
+			layout := iso8601
+			if enc.opts.timeZoneOffset {
+				layout = iso8601Z
+			}
+			if loc := enc.timeLocation(); loc != nil {
+				t = t.In(loc)
+			}
+			b.WriteString("<dateTime.iso8601>")
+			b.Write(t.AppendFormat(b.AvailableBuffer(), layout))
+			b.WriteString("</dateTime.iso8601>")
+		} else if err := enc.encodeStruct(b, val); err != nil {
+			return err
 		}
 	case reflect.Map:
-		b, err = encodeMap(val)
+		if err := enc.encodeMap(b, val); err != nil {
+			return err
+		}
 	case reflect.Slice:
-		b, err = encodeSlice(val)
+		var err error
+		if m, ok := val.Interface().(OrderedMap); ok {
+			err = enc.encodeMembers(b, m)
+		} else {
+			err = enc.encodeSlice(b, val)
+		}
+		if err != nil {
+			return err
+		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		b = fmt.Appendf(nil, "<int>%s</int>", strconv.FormatInt(val.Int(), 10))
+		// values outside the 32-bit range are not valid <int>, use <i8> instead.
+		if i := val.Int(); i < math.MinInt32 || i > math.MaxInt32 {
+			writeInt(b, "i8", i)
+		} else {
+			writeInt(b, "int", i)
+		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		b = fmt.Appendf(nil, "<i4>%s</i4>", strconv.FormatUint(val.Uint(), 10))
+		switch u := val.Uint(); {
+		case u > math.MaxInt64:
+			return fmt.Errorf("xmlrpc: value %d overflows i8", u)
+		case u > math.MaxInt32:
+			writeInt(b, "i8", int64(u))
+		case enc.opts.canonical:
+			writeInt(b, "int", int64(u))
+		default:
+			writeInt(b, "i4", int64(u))
+		}
 	case reflect.Float32, reflect.Float64:
-		b = fmt.Appendf(nil, "<double>%s</double>",
-			strconv.FormatFloat(val.Float(), 'f', -1, val.Type().Bits()))
+		f := val.Float()
+		if enc.opts.canonical {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return fmt.Errorf("xmlrpc: cannot encode %v as double", f)
+			}
+			if f == 0 {
+				f = 0 // normalize negative zero
+			}
+		}
+		b.WriteString("<double>")
+		b.Write(strconv.AppendFloat(b.AvailableBuffer(), f, 'f', -1, val.Type().Bits()))
+		b.WriteString("</double>")
 	case reflect.Bool:
 		if val.Bool() {
-			b = []byte("<boolean>1</boolean>")
+			b.WriteString("<boolean>1</boolean>")
 		} else {
-			b = []byte("<boolean>0</boolean>")
+			b.WriteString("<boolean>0</boolean>")
 		}
 	case reflect.String:
-		var buf bytes.Buffer
-
-		xml.Escape(&buf, []byte(val.String()))
-
 		if _, ok := val.Interface().(Base64); ok {
-			b = fmt.Appendf(nil, "<base64>%s</base64>", buf.String())
+			b.WriteString("<base64>")
+			xml.Escape(b, []byte(val.String()))
+			b.WriteString("</base64>")
 		} else {
-			b = fmt.Appendf(nil, "<string>%s</string>", buf.String())
+			b.WriteString("<string>")
+			xml.Escape(b, []byte(val.String()))
+			b.WriteString("</string>")
 		}
 	default:
-		return nil, fmt.Errorf("xmlrpc: unsupported type %s", val.Kind())
+		return fmt.Errorf("xmlrpc: unsupported type %s", val.Kind())
 	}
 
-	if err != nil {
-		return nil, err
+	b.WriteString("</value>")
+	return nil
+}
+
+// encodeBigInt writes the <value> element of val, a [big.Int]. Values within
+// the int64 range are encoded like an int64, larger ones as a decimal
+// <string>, since XML-RPC has no wider integer type.
+func encodeBigInt(b *bytes.Buffer, val reflect.Value) {
+	var n *big.Int
+	if val.CanAddr() {
+		n = val.Addr().Interface().(*big.Int)
+	} else {
+		v := val.Interface().(big.Int)
+		n = &v
 	}
 
-	return fmt.Appendf(nil, "<value>%s</value>", string(b)), nil
+	b.WriteString("<value>")
+	switch {
+	case !n.IsInt64():
+		b.WriteString("<string>")
+		b.Write(n.Append(b.AvailableBuffer(), 10))
+		b.WriteString("</string>")
+	case n.Int64() < math.MinInt32 || n.Int64() > math.MaxInt32:
+		writeInt(b, "i8", n.Int64())
+	default:
+		writeInt(b, "int", n.Int64())
+	}
+	b.WriteString("</value>")
+}
+
+// timeLocation returns the location times are converted to before encoding,
+// or nil if they keep their own: UTC unless [WithTimeLocation] chose another
+// location or [WithTimeZoneOffset] asked to preserve the offset.
+func (enc *encoder) timeLocation() *time.Location {
+	if enc.opts.timeLocation != nil {
+		return enc.opts.timeLocation
+	}
+	if enc.opts.timeZoneOffset {
+		return nil
+	}
+	return time.UTC
+}
+
+// writeInt writes i as decimal wrapped in an element named tag to b.
+func writeInt(b *bytes.Buffer, tag string, i int64) {
+	b.WriteByte('<')
+	b.WriteString(tag)
+	b.WriteByte('>')
+	b.Write(strconv.AppendInt(b.AvailableBuffer(), i, 10))
+	b.WriteString("</")
+	b.WriteString(tag)
+	b.WriteByte('>')
+}
+
+// writeMemberName writes the start of a struct member named name to b,
+// escaping name.
+func writeMemberName(b *bytes.Buffer, name string) {
+	b.WriteString("<member><name>")
+	xml.Escape(b, []byte(name))
+	b.WriteString("</name>")
 }
 
-func encodeStruct(structVal reflect.Value) ([]byte, error) {
-	var b bytes.Buffer
+func (enc *encoder) encodeStruct(b *bytes.Buffer, structVal reflect.Value) error {
+	if sv, ok := structValuer(structVal); ok {
+		return enc.encodeMembers(b, sv.XMLRPCStructMembers())
+	}
 
 	b.WriteString("<struct>")
 
-	structType := structVal.Type()
-	for i := 0; i < structType.NumField(); i++ {
-		fieldVal := structVal.Field(i)
-		fieldType := structType.Field(i)
+	for _, field := range structFields(structVal.Type(), enc.opts.jsonTags) {
+		fieldVal, err := structVal.FieldByIndexErr(field.index)
+		if err != nil {
+			// fields promoted through a nil embedded pointer are absent.
+			continue
+		}
+		// if the tag has the omitempty property, skip it
+		if field.omitEmpty && isEmptyValue(fieldVal) {
+			continue
+		}
+
+		writeMemberName(b, field.name)
+		if err := enc.encodeValue(b, fieldVal); err != nil {
+			return withPath(err, field.name)
+		}
+		b.WriteString("</member>")
+	}
+
+	b.WriteString("</struct>")
+
+	return nil
+}
+
+// isEmptyValue reports whether val is omitted by omitempty: a zero value, or
+// an empty slice or map. Pointers are empty only when nil.
+func isEmptyValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Slice, reflect.Map:
+		return val.Len() == 0
+	}
+	return val.IsZero()
+}
+
+// structField is a field of a struct type encoded as an XML-RPC struct member.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields returns the members of struct type t in field order. Like
+// encoding/json, unexported fields are skipped, the fields of anonymous
+// embedded structs without a name tag are promoted into the parent, and a
+// field hides promoted fields of the same name that are embedded more deeply.
+// If jsonTags is set, fields without an xmlrpc tag use their json tag.
+func structFields(t reflect.Type, jsonTags bool) []structField {
+	var all []structField
+	collectFields(t, nil, jsonTags, &all)
+
+	depth := make(map[string]int)
+	for _, f := range all {
+		if d, ok := depth[f.name]; !ok || len(f.index) < d {
+			depth[f.name] = len(f.index)
+		}
+	}
 
-		name := fieldType.Tag.Get("xmlrpc")
+	fields := make([]structField, 0, len(all))
+	seen := make(map[string]bool)
+	for _, f := range all {
+		if len(f.index) != depth[f.name] || seen[f.name] {
+			continue
+		}
+		seen[f.name] = true
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// collectFields appends the fields of struct type t to fields, recursing into
+// anonymous embedded structs. index is the index sequence of t in the parent.
+func collectFields(t reflect.Type, index []int, jsonTags bool, fields *[]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag, ok := field.Tag.Lookup("xmlrpc")
+		if !ok && jsonTags {
+			tag = field.Tag.Get("json")
+		}
+		name, opts, _ := strings.Cut(tag, ",")
 		// skip ignored fields.
 		if name == "-" {
 			continue
 		}
-		// if the tag has the omitempty property, skip it
-		if strings.HasSuffix(name, ",omitempty") && fieldVal.IsZero() {
+
+		fieldIndex := append(slices.Clone(index), i)
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != reflect.TypeFor[time.Time]() {
+				collectFields(ft, fieldIndex, jsonTags, fields)
+				continue
+			}
+		}
+		// skip unexported fields, they cannot be read or set.
+		if !field.IsExported() {
 			continue
 		}
-		name = strings.TrimSuffix(name, ",omitempty")
+
 		if name == "" {
-			name = fieldType.Name
+			name = field.Name
 		}
+		*fields = append(*fields, structField{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty"),
+		})
+	}
+}
 
-		p, err := encodeValue(fieldVal)
-		if err != nil {
-			return nil, err
-		}
+// structValuer returns the [StructValuer] implemented by val or, if val is
+// addressable, by a pointer to val.
+func structValuer(val reflect.Value) (StructValuer, bool) {
+	if !val.CanInterface() {
+		return nil, false
+	}
+	if sv, ok := val.Interface().(StructValuer); ok {
+		return sv, true
+	}
+	if val.CanAddr() {
+		sv, ok := val.Addr().Interface().(StructValuer)
+		return sv, ok
+	}
+	return nil, false
+}
 
-		fmt.Fprintf(&b, "<member><name>%s</name>", name)
-		b.Write(p)
+// textMarshaler returns the [encoding.TextMarshaler] implemented by val or,
+// if val is addressable, by a pointer to val. Times and types implementing
+// [StructValuer] keep their XML-RPC encoding.
+func textMarshaler(val reflect.Value) (encoding.TextMarshaler, bool) {
+	if !val.CanInterface() || val.Type() == reflect.TypeFor[time.Time]() {
+		return nil, false
+	}
+	if _, ok := structValuer(val); ok {
+		return nil, false
+	}
+	if tm, ok := val.Interface().(encoding.TextMarshaler); ok {
+		return tm, true
+	}
+	if val.CanAddr() {
+		tm, ok := val.Addr().Interface().(encoding.TextMarshaler)
+		return tm, ok
+	}
+	return nil, false
+}
+
+func (enc *encoder) encodeMembers(b *bytes.Buffer, members []Member) error {
+	b.WriteString("<struct>")
+
+	for _, m := range members {
+		writeMemberName(b, m.Name)
+		if err := enc.encode(b, m.Value); err != nil {
+			return withPath(err, m.Name)
+		}
 		b.WriteString("</member>")
 	}
 
 	b.WriteString("</struct>")
 
-	return b.Bytes(), nil
+	return nil
 }
 
-func encodeMap(val reflect.Value) ([]byte, error) {
+func (enc *encoder) encodeMap(b *bytes.Buffer, val reflect.Value) error {
 	t := val.Type()
 
 	if t.Key().Kind() != reflect.String {
-		return nil, fmt.Errorf(
+		return fmt.Errorf(
 			"xmlrpc: map key type %s not supported, must be string",
 			t.Key().Kind(),
 		)
 	}
 
-	var b bytes.Buffer
-
 	b.WriteString("<struct>")
 
 	keys := val.MapKeys()
@@ -139,39 +544,82 @@ func encodeMap(val reflect.Value) ([]byte, error) {
 	})
 
 	for _, key := range keys {
-		kval := val.MapIndex(key)
-
-		fmt.Fprintf(&b, "<member><name>%s</name>", key.String())
-
-		p, err := encodeValue(kval)
-		if err != nil {
-			return nil, err
+		writeMemberName(b, key.String())
+		if err := enc.encodeValue(b, val.MapIndex(key)); err != nil {
+			return withPath(err, key.String())
 		}
-
-		b.Write(p)
 		b.WriteString("</member>")
 	}
 
 	b.WriteString("</struct>")
 
-	return b.Bytes(), nil
+	return nil
 }
 
-func encodeSlice(val reflect.Value) ([]byte, error) {
-	var b bytes.Buffer
-
+func (enc *encoder) encodeSlice(b *bytes.Buffer, val reflect.Value) error {
 	b.WriteString("<array><data>")
 
 	for i := 0; i < val.Len(); i++ {
-		p, err := encodeValue(val.Index(i))
-		if err != nil {
-			return nil, err
+		if err := enc.encodeValue(b, val.Index(i)); err != nil {
+			return withPath(err, "["+strconv.Itoa(i)+"]")
 		}
-
-		b.Write(p)
 	}
 
 	b.WriteString("</data></array>")
 
-	return b.Bytes(), nil
+	return nil
+}
+
+// encodeError is an encoding error annotated with the location of the value
+// that failed, e.g. `param 2: field "Config.Handler": unsupported type func`.
+type encodeError struct {
+	// param is the index of the method call parameter, or -1 if unknown.
+	param int
+	// path holds the member names and array indexes leading to the value,
+	// outermost first.
+	path []string
+	err  error
+}
+
+func (e *encodeError) Error() string {
+	var b strings.Builder
+	b.WriteString("xmlrpc: ")
+	if e.param >= 0 {
+		fmt.Fprintf(&b, "param %d: ", e.param)
+	}
+	if len(e.path) > 0 {
+		var path strings.Builder
+		for i, elem := range e.path {
+			if i > 0 && !strings.HasPrefix(elem, "[") {
+				path.WriteByte('.')
+			}
+			path.WriteString(elem)
+		}
+		fmt.Fprintf(&b, "field %q: ", path.String())
+	}
+	b.WriteString(strings.TrimPrefix(e.err.Error(), "xmlrpc: "))
+	return b.String()
+}
+
+func (e *encodeError) Unwrap() error { return e.err }
+
+// withPath prepends elem to the path of err, annotating err first if needed.
+func withPath(err error, elem string) error {
+	e, ok := err.(*encodeError)
+	if !ok {
+		e = &encodeError{param: -1, err: err}
+	}
+	e.path = slices.Insert(e.path, 0, elem)
+	return e
+}
+
+// withParam records that err occurred encoding the method call parameter
+// with index i.
+func withParam(err error, i int) error {
+	e, ok := err.(*encodeError)
+	if !ok {
+		e = &encodeError{err: err}
+	}
+	e.param = i
+	return e
 }
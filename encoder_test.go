@@ -1,10 +1,62 @@
 package xmlrpc
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
 	"testing"
 	"time"
 )
 
+// account keeps its fields unexported and exposes them as struct members.
+type account struct {
+	id    int
+	owner string
+}
+
+func (a account) XMLRPCStructMembers() []Member {
+	return []Member{{"id", a.id}, {"owner", a.owner}, {"note", nil}}
+}
+
+// counter implements StructValuer with a pointer receiver.
+type counter struct {
+	n int
+}
+
+func (c *counter) XMLRPCStructMembers() []Member {
+	return []Member{{"count", c.n}}
+}
+
+// color is an enum implementing encoding.TextMarshaler.
+type color int
+
+const (
+	red color = iota
+	green
+)
+
+func (c color) MarshalText() ([]byte, error) {
+	switch c {
+	case red:
+		return []byte("red"), nil
+	case green:
+		return []byte("green"), nil
+	}
+	return nil, errors.New("unknown color")
+}
+
+// label implements encoding.TextMarshaler with a pointer receiver.
+type label struct {
+	text string
+}
+
+func (l *label) MarshalText() ([]byte, error) {
+	return []byte("<" + l.text + ">"), nil
+}
+
 var marshalTests = []struct {
 	name  string
 	value any
@@ -12,6 +64,12 @@ var marshalTests = []struct {
 }{
 	// primitives
 	{"int", 100, "<value><int>100</int></value>"},
+	{"int/max_i4", int64(2147483647), "<value><int>2147483647</int></value>"},
+	{"int/above_i4", int64(2147483648), "<value><i8>2147483648</i8></value>"},
+	{"int/below_i4", int64(-2147483649), "<value><i8>-2147483649</i8></value>"},
+	{"int/max_int64", int64(9223372036854775807), "<value><i8>9223372036854775807</i8></value>"},
+	{"uint", uint(100), "<value><i4>100</i4></value>"},
+	{"uint/above_i4", uint64(4294967295), "<value><i8>4294967295</i8></value>"},
 	{"string/simple", "Once upon a time", "<value><string>Once upon a time</string></value>"},
 	{
 		"string/escaped",
@@ -65,11 +123,104 @@ var marshalTests = []struct {
 
 	{"struct/empty", &struct{}{}, "<value><struct></struct></value>"},
 
+	{"struct/omitempty_nil_pointer", &struct {
+		ID    int  `xmlrpc:"id"`
+		Count *int `xmlrpc:"count,omitempty"`
+	}{ID: 1}, "<value><struct><member><name>id</name><value><int>1</int></value></member></struct></value>"},
+
+	{"struct/omitempty_pointer_to_zero", &struct {
+		ID    int  `xmlrpc:"id"`
+		Count *int `xmlrpc:"count,omitempty"`
+	}{ID: 1, Count: new(int)}, "<value><struct><member><name>id</name><value><int>1</int></value></member><member><name>count</name><value><int>0</int></value></member></struct></value>"},
+
+	{"struct/pointer_fields", &struct {
+		When  *time.Time `xmlrpc:"when"`
+		Count *int       `xmlrpc:"count"`
+		Note  *string    `xmlrpc:"note"`
+	}{When: &[]time.Time{time.Date(2013, 12, 9, 21, 0, 12, 0, time.UTC)}[0], Count: &[]int{7}[0]}, "<value><struct><member><name>when</name><value><dateTime.iso8601>20131209T21:00:12</dateTime.iso8601></value></member><member><name>count</name><value><int>7</int></value></member><member><name>note</name><value/></member></struct></value>"},
+
+	{"pointer/double", &[]*int{&[]int{5}[0]}[0], "<value><int>5</int></value>"},
+	{"pointer/double_nil", &[]*int{nil}[0], "<value/>"},
+	{"pointer/interface", &[]any{&[]string{"x"}[0]}[0], "<value><string>x</string></value>"},
+
+	{"struct/omitempty_empty_slice", &struct {
+		ID   int      `xmlrpc:"id"`
+		Tags []string `xmlrpc:"tags,omitempty"`
+	}{ID: 1, Tags: []string{}}, "<value><struct><member><name>id</name><value><int>1</int></value></member></struct></value>"},
+
+	{"struct/omitempty_empty_map", &struct {
+		ID     int            `xmlrpc:"id"`
+		Filter map[string]any `xmlrpc:"filter,omitempty"`
+	}{ID: 1, Filter: map[string]any{}}, "<value><struct><member><name>id</name><value><int>1</int></value></member></struct></value>"},
+
+	{"struct/omitempty_non_empty_slice", &struct {
+		Tags []string `xmlrpc:"tags,omitempty"`
+	}{Tags: []string{"a"}}, "<value><struct><member><name>tags</name><value><array><data><value><string>a</string></value></data></array></value></member></struct></value>"},
+
+	{"struct/omitempty_zero_value", &struct {
+		ID    int `xmlrpc:"id"`
+		Count int `xmlrpc:"count,omitempty"`
+	}{ID: 1}, "<value><struct><member><name>id</name><value><int>1</int></value></member></struct></value>"},
+
+	{"struct/skip_unexported", &struct {
+		ID     int `xmlrpc:"id"`
+		secret string
+		hidden func()
+	}{ID: 1, secret: "s"}, "<value><struct><member><name>id</name><value><int>1</int></value></member></struct></value>"},
+
 	{"struct/skip_field", &struct {
 		ID   int    `xmlrpc:"id"`
 		Name string `xmlrpc:"-"`
 	}{ID: 123, Name: "kolo"}, "<value><struct><member><name>id</name><value><int>123</int></value></member></struct></value>"},
 
+	{
+		"struct/valuer",
+		account{id: 7, owner: "kolo"},
+		"<value><struct><member><name>id</name><value><int>7</int></value></member><member><name>owner</name><value><string>kolo</string></value></member><member><name>note</name><value/></member></struct></value>",
+	},
+	{
+		"struct/valuer_pointer",
+		&counter{n: 3},
+		"<value><struct><member><name>count</name><value><int>3</int></value></member></struct></value>",
+	},
+	{
+		"struct/valuer_nested",
+		map[string]any{"acct": account{id: 1, owner: "a"}},
+		"<value><struct><member><name>acct</name><value><struct><member><name>id</name><value><int>1</int></value></member><member><name>owner</name><value><string>a</string></value></member><member><name>note</name><value/></member></struct></value></member></struct></value>",
+	},
+
+	{
+		"ordered_map",
+		OrderedMap{{"zeta", 1}, {"alpha", "a"}, {"mid", OrderedMap{{"y", true}, {"x", nil}}}},
+		"<value><struct><member><name>zeta</name><value><int>1</int></value></member><member><name>alpha</name><value><string>a</string></value></member><member><name>mid</name><value><struct><member><name>y</name><value><boolean>1</boolean></value></member><member><name>x</name><value/></member></struct></value></member></struct></value>",
+	},
+	{"ordered_map/empty", OrderedMap{}, "<value><struct></struct></value>"},
+	{
+		"ordered_map/escaped_name",
+		OrderedMap{{"a<b&c", 1}},
+		"<value><struct><member><name>a&lt;b&amp;c</name><value><int>1</int></value></member></struct></value>",
+	},
+	{
+		"map/escaped_name",
+		map[string]any{"x>y": "z"},
+		"<value><struct><member><name>x&gt;y</name><value><string>z</string></value></member></struct></value>",
+	},
+
+	// encoding.TextMarshaler
+	{"text_marshaler/ip", net.ParseIP("192.0.2.1"), "<value><string>192.0.2.1</string></value>"},
+	{"text_marshaler/enum", green, "<value><string>green</string></value>"},
+	{"text_marshaler/enum_pointer", &[]color{red}[0], "<value><string>red</string></value>"},
+	{"text_marshaler/pointer_receiver", &label{"a&b"}, "<value><string>&lt;a&amp;b&gt;</string></value>"},
+	{
+		"text_marshaler/fields",
+		&struct {
+			Color color    `xmlrpc:"color"`
+			Label label    `xmlrpc:"label"`
+			IPs   []net.IP `xmlrpc:"ips"`
+		}{green, label{"x"}, []net.IP{net.IPv4(10, 0, 0, 1)}},
+		"<value><struct><member><name>color</name><value><string>green</string></value></member><member><name>label</name><value><string>&lt;x&gt;</string></value></member><member><name>ips</name><value><array><data><value><string>10.0.0.1</string></value></data></array></value></member></struct></value>",
+	},
+
 	// map
 	{
 		"map/simple",
@@ -111,12 +262,218 @@ func TestMarshal(t *testing.T) {
 	}
 }
 
+func TestMarshalTimeZoneOffset(t *testing.T) {
+	t.Parallel()
+
+	enc := &encoder{opts: encodeOptions{timeZoneOffset: true}}
+
+	tests := []struct {
+		name  string
+		value time.Time
+		xml   string
+	}{
+		{
+			"positive_offset",
+			time.Date(2013, 12, 9, 21, 0, 12, 0, time.FixedZone("", 3600)),
+			"<value><dateTime.iso8601>20131209T21:00:12+01:00</dateTime.iso8601></value>",
+		},
+		{
+			"utc",
+			time.Date(2013, 12, 9, 21, 0, 12, 0, time.UTC),
+			"<value><dateTime.iso8601>20131209T21:00:12Z</dateTime.iso8601></value>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := enc.marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal error: %v", err)
+			}
+			if string(b) != tt.xml {
+				t.Fatalf("marshal error:\nexpected: %s\n     got: %s", tt.xml, string(b))
+			}
+		})
+	}
+}
+
+func TestMarshalTimeLocation(t *testing.T) {
+	t.Parallel()
+
+	value := time.Date(2013, 12, 9, 21, 0, 12, 0, time.FixedZone("", 3600))
+
+	tests := []struct {
+		name string
+		opts encodeOptions
+		xml  string
+	}{
+		{
+			"default_utc",
+			encodeOptions{},
+			"<value><dateTime.iso8601>20131209T20:00:12</dateTime.iso8601></value>",
+		},
+		{
+			"utc_with_offset",
+			encodeOptions{timeZoneOffset: true, timeLocation: time.UTC},
+			"<value><dateTime.iso8601>20131209T20:00:12Z</dateTime.iso8601></value>",
+		},
+		{
+			"preserve_offset",
+			encodeOptions{timeZoneOffset: true},
+			"<value><dateTime.iso8601>20131209T21:00:12+01:00</dateTime.iso8601></value>",
+		},
+		{
+			"fixed_zone",
+			encodeOptions{timeLocation: time.FixedZone("", -5*3600)},
+			"<value><dateTime.iso8601>20131209T15:00:12</dateTime.iso8601></value>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			enc := &encoder{opts: tt.opts}
+			b, err := enc.marshal(value)
+			if err != nil {
+				t.Fatalf("marshal error: %v", err)
+			}
+			if string(b) != tt.xml {
+				t.Fatalf("marshal error:\nexpected: %s\n     got: %s", tt.xml, string(b))
+			}
+		})
+	}
+}
+
+func TestMarshalUintOverflow(t *testing.T) {
+	t.Parallel()
+
+	if _, err := marshal(uint64(math.MaxUint64)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestMarshalTextMarshalerError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := marshal(color(42)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	t.Parallel()
+
+	enc := &encoder{opts: encodeOptions{canonical: true}}
+
+	tests := []struct {
+		name  string
+		value any
+		xml   string
+	}{
+		{"uint", uint(100), "<value><int>100</int></value>"},
+		{"uint/above_i4", uint64(4294967295), "<value><i8>4294967295</i8></value>"},
+		{"double/negative_zero", math.Copysign(0, -1), "<value><double>0</double></value>"},
+		{"string/empty", "", "<value><string></string></value>"},
+		{"array/nil", []int(nil), "<value><array><data></data></array></value>"},
+		{"map/nil", map[string]int(nil), "<value><struct></struct></value>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := enc.marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal error: %v", err)
+			}
+			if string(b) != tt.xml {
+				t.Fatalf("marshal error:\nexpected: %s\n     got: %s", tt.xml, string(b))
+			}
+		})
+	}
+
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := enc.marshal(f); err == nil {
+			t.Errorf("expected error for %v, got nil", f)
+		}
+	}
+}
+
+func TestMarshalCanonicalGolden(t *testing.T) {
+	t.Parallel()
+
+	// canonicalGolden is the SHA-256 of the canonical method call below.
+	// It must only change together with a deliberate change of the encoding.
+	const canonicalGolden = "dbed6ffa0b3bf30b9a02438ba63fbb7022c2ddc1d5dcd841da916f4d97465557"
+
+	keys := []string{"id", "name", "tags", "scores", "created", "meta", "nothing", "empty"}
+	values := map[string]any{
+		"id":      uint32(42),
+		"name":    "Mike & Mick <London>",
+		"tags":    []string{"a", "b", ""},
+		"scores":  []float64{1.5, math.Copysign(0, -1), -2},
+		"created": time.Date(2013, 12, 9, 21, 0, 12, 0, time.UTC),
+		"meta":    map[string]any{"z": true, "a": int64(1) << 40},
+		"nothing": nil,
+		"empty":   map[string]any{},
+	}
+
+	enc := &encoder{opts: encodeOptions{canonical: true}}
+	for i := range 20 {
+		// Build the map with a different insertion order on every run.
+		m := make(map[string]any, len(keys))
+		for j := range keys {
+			k := keys[(i+j)%len(keys)]
+			m[k] = values[k]
+		}
+
+		var b bytes.Buffer
+		if err := enc.writeMethodCall(&b, "report.submit", m, Base64("aGk=")); err != nil {
+			t.Fatalf("writeMethodCall error: %v", err)
+		}
+
+		sum := sha256.Sum256(b.Bytes())
+		if got := hex.EncodeToString(sum[:]); got != canonicalGolden {
+			t.Fatalf("run %d: canonical hash changed:\nexpected: %s\n     got: %s\nbody: %s",
+				i, canonicalGolden, got, b.String())
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	enc := NewEncoder(&b, WithTimeZoneOffset())
+
+	for _, v := range []any{42, "a&b", time.Date(2013, 12, 9, 21, 0, 12, 0, time.UTC)} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode(%v) error: %v", v, err)
+		}
+	}
+	if err := enc.Encode([]any{1, uint64(math.MaxUint64)}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	const expected = "<value><int>42</int></value>" +
+		"<value><string>a&amp;b</string></value>" +
+		"<value><dateTime.iso8601>20131209T21:00:12Z</dateTime.iso8601></value>"
+	if b.String() != expected {
+		t.Fatalf("Encode error:\nexpected: %s\n     got: %s", expected, b.String())
+	}
+}
+
 func BenchmarkMarshal(b *testing.B) {
 	benchmarks := []struct {
 		name  string
 		value any
 	}{
 		{"int", 12345},
+		{"i8", int64(1) << 40},
+		{"double", 3.14159},
 		{"string", "Hello, World!"},
 		{"bool", true},
 		{"struct", &struct {
@@ -129,6 +486,7 @@ func BenchmarkMarshal(b *testing.B) {
 
 	for _, bm := range benchmarks {
 		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
 			for i := 0; i < b.N; i++ {
 				if _, err := marshal(bm.value); err != nil {
 					b.Fatal(err)
@@ -138,6 +496,67 @@ func BenchmarkMarshal(b *testing.B) {
 	}
 }
 
+func BenchmarkMarshalParallel(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		value any
+	}{
+		{"struct", &struct {
+			Title  string
+			Amount int
+			Tags   []string
+		}{"Test Book", 100, []string{"a", "b", "c"}}},
+		{"map", map[string]any{"key1": "value1", "key2": 123, "key3": []int{1, 2, 3}}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := marshal(bm.value); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	value := &struct {
+		Title  string
+		Amount int
+		Tags   []string
+	}{"Test Book", 100, []string{"a", "b", "c"}}
+
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		var w bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			w.Reset()
+			p, err := marshal(value)
+			if err != nil {
+				b.Fatal(err)
+			}
+			w.Write(p)
+		}
+	})
+
+	b.Run("Encoder", func(b *testing.B) {
+		b.ReportAllocs()
+		var w bytes.Buffer
+		enc := NewEncoder(&w)
+		for i := 0; i < b.N; i++ {
+			w.Reset()
+			if err := enc.Encode(value); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func FuzzMarshal(f *testing.F) {
 	f.Add("hello")
 	f.Add("")
@@ -0,0 +1,90 @@
+package xmlrpc
+
+import (
+	"context"
+	"fmt"
+)
+
+// defaultPingMethod is the method called by [Client.Ping] unless another one
+// is set with [WithPingMethod].
+const defaultPingMethod = "system.listMethods"
+
+// Ping checks whether the server is alive, e.g. for readiness probes, by
+// calling the method set with [WithPingMethod], system.listMethods by
+// default, without arguments. It returns nil for any valid response, ignoring
+// its content; as a fault is a valid response, servers that do not implement
+// the method are considered alive as well.
+func (c *Client) Ping(ctx context.Context) error {
+	err := c.CallContext(ctx, c.pingMethod, nil, nil)
+	if _, ok := err.(FaultError); ok {
+		return nil
+	}
+	return err
+}
+
+// ListMethods returns the names of the methods implemented by the server
+// using the system.listMethods introspection call.
+func (c *Client) ListMethods(ctx context.Context) ([]string, error) {
+	var methods []string
+	if err := c.CallContext(ctx, "system.listMethods", nil, &methods); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// MethodSignature returns the signatures of the given method using the
+// system.methodSignature introspection call. Each signature lists the return
+// type followed by the parameter types.
+//
+// Servers that do not support signatures return a string instead of an
+// array; in that case MethodSignature returns nil and no error.
+func (c *Client) MethodSignature(ctx context.Context, method string) ([][]string, error) {
+	var result any
+	if err := c.CallContext(ctx, "system.methodSignature", method, &result); err != nil {
+		return nil, err
+	}
+
+	list, ok := result.([]any)
+	if !ok {
+		if _, ok := result.(string); ok {
+			return nil, nil
+		}
+		return nil, TypeMismatchError(
+			fmt.Sprintf("xmlrpc: unexpected method signature type %T", result),
+		)
+	}
+
+	signatures := make([][]string, 0, len(list))
+	for _, item := range list {
+		types, ok := item.([]any)
+		if !ok {
+			return nil, TypeMismatchError(
+				fmt.Sprintf("xmlrpc: unexpected method signature type %T", item),
+			)
+		}
+
+		signature := make([]string, 0, len(types))
+		for _, typ := range types {
+			name, ok := typ.(string)
+			if !ok {
+				return nil, TypeMismatchError(
+					fmt.Sprintf("xmlrpc: unexpected method signature type %T", typ),
+				)
+			}
+			signature = append(signature, name)
+		}
+		signatures = append(signatures, signature)
+	}
+
+	return signatures, nil
+}
+
+// MethodHelp returns the documentation of the given method using the
+// system.methodHelp introspection call.
+func (c *Client) MethodHelp(ctx context.Context, method string) (string, error) {
+	var help string
+	if err := c.CallContext(ctx, "system.methodHelp", method, &help); err != nil {
+		return "", err
+	}
+	return help, nil
+}
@@ -0,0 +1,169 @@
+package xmlrpc
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newIntrospectionClient(t *testing.T, wantMethod, response string) *Client {
+	t.Helper()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "<methodName>"+wantMethod+"</methodName>") {
+			t.Errorf("expected call to %s, got %s", wantMethod, body)
+		}
+		if _, err := io.WriteString(
+			w,
+			`<?xml version="1.0"?><methodResponse><params><param>`+response+`</param></params></methodResponse>`,
+		); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestListMethods(t *testing.T) {
+	t.Parallel()
+
+	client := newIntrospectionClient(t, "system.listMethods",
+		`<value><array><data><value><string>system.listMethods</string></value><value><string>math.add</string></value></data></array></value>`,
+	)
+
+	methods, err := client.ListMethods(t.Context())
+	if err != nil {
+		t.Fatalf("ListMethods error: %v", err)
+	}
+	if want := []string{"system.listMethods", "math.add"}; !reflect.DeepEqual(methods, want) {
+		t.Fatalf("expected %v, got %v", want, methods)
+	}
+}
+
+func TestMethodSignature(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		response string
+		want     [][]string
+	}{
+		{
+			"signatures",
+			`<value><array><data><value><array><data><value><string>int</string></value><value><string>int</string></value><value><string>int</string></value></data></array></value></data></array></value>`,
+			[][]string{{"int", "int", "int"}},
+		},
+		{
+			"not_supported",
+			`<value><string>signatures not supported</string></value>`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newIntrospectionClient(t, "system.methodSignature", tt.response)
+
+			signatures, err := client.MethodSignature(t.Context(), "math.add")
+			if err != nil {
+				t.Fatalf("MethodSignature error: %v", err)
+			}
+			if !reflect.DeepEqual(signatures, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, signatures)
+			}
+		})
+	}
+}
+
+func TestMethodHelp(t *testing.T) {
+	t.Parallel()
+
+	client := newIntrospectionClient(t, "system.methodHelp",
+		`<value><string>Adds two integers.</string></value>`,
+	)
+
+	help, err := client.MethodHelp(t.Context(), "math.add")
+	if err != nil {
+		t.Fatalf("MethodHelp error: %v", err)
+	}
+	if help != "Adds two integers." {
+		t.Fatalf("expected 'Adds two integers.', got %q", help)
+	}
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantMethod string
+		status     int
+		response   string
+		wantErr    bool
+	}{
+		{
+			"default_method", nil, "system.listMethods", http.StatusOK,
+			`<methodResponse><params><param><value><array><data></data></array></value></param></params></methodResponse>`,
+			false,
+		},
+		{
+			"custom_method", []Option{WithPingMethod("health.check")}, "health.check", http.StatusOK,
+			`<methodResponse><params><param><value><boolean>1</boolean></value></param></params></methodResponse>`,
+			false,
+		},
+		{
+			"fault", nil, "system.listMethods", http.StatusOK,
+			`<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value><int>-32601</int></value></member>` +
+				`<member><name>faultString</name><value><string>method not found</string></value></member>` +
+				`</struct></value></fault></methodResponse>`,
+			false,
+		},
+		{"unavailable", nil, "system.listMethods", http.StatusServiceUnavailable, "unavailable", true},
+		{"invalid_response", nil, "system.listMethods", http.StatusOK, "<html>maintenance</html>", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(body), "<methodName>"+tt.wantMethod+"</methodName>") {
+					t.Errorf("expected call to %s, got %s", tt.wantMethod, body)
+				}
+				w.WriteHeader(tt.status)
+				if _, err := io.WriteString(w, tt.response); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			client, err := NewClientWithOptions(ts.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewClientWithOptions error: %v", err)
+			}
+			defer client.Close()
+
+			if err := client.Ping(t.Context()); (err != nil) != tt.wantErr {
+				t.Fatalf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// MultiCallRequest is a single call of a [Client.MultiCall] batch.
+type MultiCallRequest struct {
+	// Method is the name of the method to call.
+	Method string
+	// Args are the arguments of the call, as passed to [Client.CallContext].
+	Args any
+	// Reply is an optional pointer the result of the call is decoded into.
+	Reply any
+}
+
+// MultiCallResult is the outcome of a single call of a [Client.MultiCall] batch.
+// Exactly one of Value and Fault is set.
+type MultiCallResult struct {
+	// Value is the Reply of the request the result was decoded into or, if
+	// Reply was nil, the result decoded as described for [Unmarshal].
+	Value any
+	// Fault is the fault returned by the call, if it failed.
+	Fault *FaultError
+
+	// raw holds the <array> element wrapping a successful result.
+	raw    []byte
+	decode decodeOptions
+}
+
+// Decode decodes the result of the call into v like [Unmarshal], independently
+// of any Reply of the request, e.g. to decode results of different types
+// after the batch. If the call failed, Decode returns its [FaultError].
+func (r MultiCallResult) Decode(v any) error {
+	if r.Fault != nil {
+		return *r.Fault
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Pointer || val.IsNil() {
+		return fmt.Errorf("xmlrpc: Decode requires a non-nil pointer, got %T", v)
+	}
+	if r.raw == nil {
+		return errors.New("xmlrpc: multicall result holds no value")
+	}
+	// The result is the first <value> inside the array.
+	return newDecoder(bytes.NewReader(r.raw), r.decode).unmarshal(v)
+}
+
+// MultiCall invokes several methods in a single request using the
+// system.multicall extension and returns their results in the order of calls.
+//
+// Per the multicall convention, the server returns a result for each call
+// either wrapped in a single-element array or as a fault struct; a failed call
+// does not affect the others. If the server returns a fault for the whole
+// batch, MultiCall returns it as a [FaultError] and no results.
+func (c *Client) MultiCall(ctx context.Context, calls ...MultiCallRequest) ([]MultiCallResult, error) {
+	batch := make([]OrderedMap, len(calls))
+	for i, call := range calls {
+		if err := checkReply(call.Reply); err != nil {
+			return nil, fmt.Errorf("xmlrpc: multicall request %d: %w", i, err)
+		}
+		params := requestArgs(call.Args)
+		if params == nil {
+			params = []any{}
+		}
+		batch[i] = OrderedMap{{"methodName", call.Method}, {"params", params}}
+	}
+
+	results := make([]MultiCallResult, 0, len(calls))
+	stream := arrayStream(func(dec *decoder) error {
+		if len(results) == len(calls) {
+			return fmt.Errorf("xmlrpc: multicall returned more than %d results", len(calls))
+		}
+		result, err := dec.decodeMultiCallResult(calls[len(results)].Reply)
+		if err != nil {
+			return fmt.Errorf("xmlrpc: multicall result %d: %w", len(results), err)
+		}
+		results = append(results, result)
+		return nil
+	})
+
+	if err := c.CallContext(ctx, "system.multicall", []any{batch}, stream); err != nil {
+		return nil, err
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("xmlrpc: multicall returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
+
+// decodeMultiCallResult decodes a single element of a multicall response,
+// either a single-element array holding the result or a fault struct.
+// It must be called after the <value> start element has been read and
+// consumes the matching </value> end element.
+func (dec *decoder) decodeMultiCallResult(reply any) (MultiCallResult, error) {
+	var raw struct {
+		Inner []byte `xml:",innerxml"`
+	}
+	start := xml.StartElement{Name: xml.Name{Local: "value"}}
+	if err := dec.DecodeElement(&raw, &start); err != nil {
+		return MultiCallResult{}, err
+	}
+
+	kind, err := rootElement(raw.Inner)
+	if err != nil {
+		return MultiCallResult{}, err
+	}
+
+	switch kind {
+	case "array":
+		var value any
+		target := reply
+		if target == nil {
+			target = &value
+		}
+		// The result is the first <value> inside the array.
+		if err := newDecoder(bytes.NewReader(raw.Inner), dec.opts).unmarshal(target); err != nil {
+			return MultiCallResult{}, err
+		}
+		result := MultiCallResult{Value: reply, raw: raw.Inner, decode: dec.opts}
+		if reply == nil {
+			result.Value = value
+		}
+		return result, nil
+	case "struct":
+		// decodeFaultValue expects the fault struct wrapped in a <value>.
+		data := slices.Concat([]byte("<value>"), raw.Inner, []byte("</value>"))
+		var fault FaultError
+		if err := newDecoder(bytes.NewReader(data), dec.opts).decodeFaultValue(&fault); err != nil {
+			return MultiCallResult{}, err
+		}
+		return MultiCallResult{Fault: &fault}, nil
+	default:
+		return MultiCallResult{}, TypeMismatchError(
+			fmt.Sprintf("xmlrpc: unexpected multicall result type %q", kind),
+		)
+	}
+}
@@ -0,0 +1,216 @@
+package xmlrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultiCall(t *testing.T) {
+	t.Parallel()
+
+	var receivedBody string
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receivedBody = string(body)
+		if _, err := io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>
+			<value><array><data><value><int>3</int></value></data></array></value>
+			<value><struct>
+				<member><name>faultCode</name><value><int>-32601</int></value></member>
+				<member><name>faultString</name><value><string>method not found</string></value></member>
+			</struct></value>
+			<value><array><data><value><struct><member><name>name</name><value><string>kolo</string></value></member></struct></value></data></array></value>
+			<value><array><data><value><array><data><value><string>a</string></value></data></array></value></data></array></value>
+		</data></array></value></param></params></methodResponse>`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	var sum int
+	var tags []string
+	results, err := client.MultiCall(context.Background(),
+		MultiCallRequest{Method: "math.add", Args: []any{1, 2}, Reply: &sum},
+		MultiCallRequest{Method: "no.such"},
+		MultiCallRequest{Method: "user.get", Args: 7},
+		MultiCallRequest{Method: "tags.list", Reply: &tags},
+	)
+	if err != nil {
+		t.Fatalf("MultiCall error: %v", err)
+	}
+
+	const wantRequest = `<methodName>system.multicall</methodName><params><param><value><array><data>` +
+		`<value><struct><member><name>methodName</name><value><string>math.add</string></value></member>` +
+		`<member><name>params</name><value><array><data><value><int>1</int></value><value><int>2</int></value></data></array></value></member></struct></value>` +
+		`<value><struct><member><name>methodName</name><value><string>no.such</string></value></member>` +
+		`<member><name>params</name><value><array><data></data></array></value></member></struct></value>`
+	if !strings.Contains(receivedBody, wantRequest) {
+		t.Errorf("unexpected request body: %s", receivedBody)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if results[0].Fault != nil || results[0].Value != &sum || sum != 3 {
+		t.Errorf("result 0: expected sum 3, got %+v (sum=%d)", results[0], sum)
+	}
+	if f := results[1].Fault; f == nil || f.Code != -32601 || f.String != "method not found" || results[1].Value != nil {
+		t.Errorf("result 1: expected fault -32601, got %+v", results[1])
+	}
+	if want := map[string]any{"name": "kolo"}; results[2].Fault != nil || !reflect.DeepEqual(results[2].Value, want) {
+		t.Errorf("result 2: expected %v, got %+v", want, results[2])
+	}
+	if results[3].Fault != nil || !reflect.DeepEqual(tags, []string{"a"}) {
+		t.Errorf("result 3: expected tags [a], got %+v (tags=%v)", results[3], tags)
+	}
+}
+
+func TestMultiCallBatchFault(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, `<?xml version="1.0"?><methodResponse><fault><value><struct>
+			<member><name>faultCode</name><value><int>-32601</int></value></member>
+			<member><name>faultString</name><value><string>system.multicall not supported</string></value></member>
+		</struct></value></fault></methodResponse>`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	results, err := client.MultiCall(context.Background(), MultiCallRequest{Method: "a"}, MultiCallRequest{Method: "b"})
+	var fault FaultError
+	if !errors.As(err, &fault) || fault.Code != -32601 {
+		t.Fatalf("expected batch fault -32601, got %v", err)
+	}
+	if results != nil {
+		t.Fatalf("expected no results, got %+v", results)
+	}
+}
+
+func TestMultiCallResultCount(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>
+			<value><array><data><value><int>1</int></value></data></array></value>
+		</data></array></value></param></params></methodResponse>`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.MultiCall(context.Background(), MultiCallRequest{Method: "a"}, MultiCallRequest{Method: "b"}); err == nil {
+		t.Fatal("expected error for missing result, got nil")
+	}
+	if _, err := client.MultiCall(context.Background()); err == nil {
+		t.Fatal("expected error for extra result, got nil")
+	}
+}
+
+func TestMultiCallResultDecode(t *testing.T) {
+	t.Parallel()
+
+	// A system.multicall response as sent by WordPress.
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+  <params>
+    <param>
+      <value>
+      <array><data>
+  <value><array><data>
+  <value><struct>
+  <member><name>post_id</name><value><string>42</string></value></member>
+  <member><name>post_title</name><value><string>Hello world!</string></value></member>
+  <member><name>post_status</name><value><string>publish</string></value></member>
+</struct></value>
+</data></array></value>
+  <value><struct>
+  <member><name>faultCode</name><value><int>403</int></value></member>
+  <member><name>faultString</name><value><string>Sorry, you are not allowed to edit this post.</string></value></member>
+</struct></value>
+  <value><array><data>
+  <value><boolean>1</boolean></value>
+</data></array></value>
+</data></array>
+      </value>
+    </param>
+  </params>
+</methodResponse>
+`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+
+	results, err := client.MultiCall(context.Background(),
+		MultiCallRequest{Method: "wp.getPost", Args: []any{1, "admin", "secret", 42}},
+		MultiCallRequest{Method: "wp.editPost", Args: []any{1, "admin", "secret", 7, map[string]any{}}},
+		MultiCallRequest{Method: "wp.deletePost", Args: []any{1, "admin", "secret", 43}},
+	)
+	if err != nil {
+		t.Fatalf("MultiCall error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	type post struct {
+		ID     string `xmlrpc:"post_id"`
+		Title  string `xmlrpc:"post_title"`
+		Status string `xmlrpc:"post_status"`
+	}
+	var p post
+	if err := results[0].Decode(&p); err != nil {
+		t.Fatalf("result 0: Decode error: %v", err)
+	}
+	if want := (post{ID: "42", Title: "Hello world!", Status: "publish"}); p != want {
+		t.Errorf("result 0: expected %+v, got %+v", want, p)
+	}
+
+	var edited bool
+	err = results[1].Decode(&edited)
+	if !errors.Is(err, NewFault(403, "")) {
+		t.Fatalf("result 1: expected fault 403, got %v", err)
+	}
+	var fault FaultError
+	if !errors.As(err, &fault) || fault.String != "Sorry, you are not allowed to edit this post." {
+		t.Errorf("result 1: unexpected fault %v", err)
+	}
+
+	var deleted bool
+	if err := results[2].Decode(&deleted); err != nil || !deleted {
+		t.Errorf("result 2: expected true, got %v (err=%v)", deleted, err)
+	}
+	if err := results[2].Decode(nil); err == nil {
+		t.Error("expected error decoding into nil, got nil")
+	}
+}
@@ -1,12 +1,16 @@
 package xmlrpc
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"unicode/utf8"
 )
 
 // NewRequest creates an [http.Request] for an XML-RPC call to the given URL.
@@ -18,65 +22,262 @@ func NewRequest(url string, method string, args any) (*http.Request, error) {
 
 // NewRequestContext creates an [http.Request] with context for an XML-RPC call to the given URL.
 // The method parameter is the XML-RPC method name, and args contains the arguments
-// to pass to the remote method.
+// to pass to the remote method. A nil args produces a call without parameters;
+// use []any{nil} to pass a single nil parameter.
 func NewRequestContext(
 	ctx context.Context,
 	url string,
 	method string,
 	args any,
 ) (*http.Request, error) {
-	var t []any
-	var ok bool
-	if t, ok = args.([]any); !ok {
-		if args != nil {
-			t = []any{args}
+	return newRequest(ctx, url, method, args, encodeOptions{}, compressOptions{})
+}
+
+// compressOptions configures compression of request bodies.
+type compressOptions struct {
+	// encoding is the Content-Encoding to apply, or empty for none.
+	encoding string
+	// threshold is the minimum body size in bytes that is compressed.
+	threshold int
+}
+
+// newRequest creates a buffered [http.Request] encoding args with opts.
+// The body is compressed if compress is configured and the body is large enough.
+func newRequest(
+	ctx context.Context,
+	url string,
+	method string,
+	args any,
+	opts encodeOptions,
+	compress compressOptions,
+) (*http.Request, error) {
+	var b bytes.Buffer
+	enc := &encoder{opts: opts}
+	if err := enc.writeMethodCall(&b, method, requestArgs(args)...); err != nil {
+		return nil, err
+	}
+	body := b.Bytes()
+
+	compressed := compress.encoding != "" && len(body) >= compress.threshold
+	if compressed {
+		var zb bytes.Buffer
+		zw := gzip.NewWriter(&zb)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
 		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		body = zb.Bytes()
 	}
 
-	body, err := EncodeMethodCall(method, t...)
+	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	request.Header.Set("Content-Type", "text/xml")
+	request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	if compressed {
+		request.Header.Set("Content-Encoding", compress.encoding)
+	}
+
+	return request, nil
+}
+
+// newStreamingRequest creates an [http.Request] whose body is encoded while it
+// is being sent instead of being buffered up front. The request has no
+// Content-Length and is sent using chunked transfer encoding. GetBody is set
+// so the transport can re-obtain the body for retries and redirects.
+// If compression is configured, the body is always compressed since its size
+// is not known in advance.
+func newStreamingRequest(
+	ctx context.Context,
+	url string,
+	method string,
+	args any,
+	opts encodeOptions,
+	compress compressOptions,
+) (*http.Request, error) {
+	t := requestArgs(args)
+	enc := &encoder{opts: opts}
+
+	getBody := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			var w io.Writer = pw
+			var zw *gzip.Writer
+			if compress.encoding != "" {
+				zw = gzip.NewWriter(pw)
+				w = zw
+			}
+
+			bw := bufio.NewWriter(w)
+			err := wrapStage(ErrEncode, enc.writeMethodCall(bw, method, t...))
+			if err == nil {
+				err = bw.Flush()
+			}
+			if err == nil && zw != nil {
+				err = zw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+
+	body, _ := getBody()
+	request, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
+		body.Close()
 		return nil, err
 	}
 
+	request.GetBody = getBody
 	request.Header.Set("Content-Type", "text/xml")
-	request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	if compress.encoding != "" {
+		request.Header.Set("Content-Encoding", compress.encoding)
+	}
 
 	return request, nil
 }
 
+// requestArgs converts the args passed to a call into the list of parameters.
+func requestArgs(args any) []any {
+	if t, ok := args.([]any); ok {
+		return t
+	}
+	if args != nil {
+		return []any{args}
+	}
+	return nil
+}
+
 // EncodeMethodCall encodes an XML-RPC method call with the given method name
-// and arguments into XML bytes.
+// and arguments into XML bytes. If an argument cannot be encoded, the error
+// names the zero-based index of the argument and the path to the failing
+// value, e.g. `param 2: field "Config.Handler": unsupported type func`.
 func EncodeMethodCall(method string, args ...any) ([]byte, error) {
 	var b bytes.Buffer
-	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><methodCall><methodName>`)
-	if err := xml.EscapeText(&b, []byte(method)); err != nil {
-		return nil, fmt.Errorf("xmlrpc: failed to encode method name: %w", err)
+	if err := (&encoder{}).writeMethodCall(&b, method, args...); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeMethodCall decodes an XML-RPC method call, e.g. a request body
+// received by a server or proxy, into its method name and parameters. The
+// parameters are decoded as described for [Unmarshal] for interface values.
+// params is nil if the call has no <params> element and empty if it has no
+// parameters.
+//
+// Decoding options such as [WithThousandsSeparators] may be passed in opts;
+// options unrelated to decoding are ignored.
+func DecodeMethodCall(data []byte, opts ...Option) (method string, params []any, err error) {
+	options := &clientOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return newDecoder(bytes.NewReader(data), options.decode).decodeMethodCall()
+}
+
+// writeMethodCall writes an XML-RPC method call to w. Arguments are encoded
+// and written one at a time, so only a single encoded argument is held in
+// memory at once.
+func (enc *encoder) writeMethodCall(w io.Writer, method string, args ...any) error {
+	if _, err := io.WriteString(
+		w,
+		`<?xml version="1.0" encoding="UTF-8"?><methodCall><methodName>`,
+	); err != nil {
+		return err
+	}
+	if err := xml.EscapeText(w, []byte(method)); err != nil {
+		return fmt.Errorf("xmlrpc: failed to encode method name: %w", err)
+	}
+	if _, err := io.WriteString(w, "</methodName>"); err != nil {
+		return err
 	}
-	b.WriteString("</methodName>")
 
 	if args != nil {
-		b.WriteString("<params>")
+		if _, err := io.WriteString(w, "<params>"); err != nil {
+			return err
+		}
 
-		for _, arg := range args {
-			p, err := marshal(arg)
-			if err != nil {
-				return nil, fmt.Errorf("xmlrpc: failed to encode argument: %w", err)
+		// Encode directly into buffers, otherwise through a buffer holding
+		// a single parameter. Strings are escaped straight to w in chunks,
+		// so a large string is never held twice.
+		b, direct := w.(*bytes.Buffer)
+		if !direct {
+			b = getBuffer()
+			defer putBuffer(b)
+		}
+		for i, arg := range args {
+			switch s := arg.(type) {
+			case string:
+				if err := writeStringParam(w, "string", s); err != nil {
+					return err
+				}
+				continue
+			case Base64:
+				if err := writeStringParam(w, "base64", string(s)); err != nil {
+					return err
+				}
+				continue
 			}
 
+			if !direct {
+				b.Reset()
+			}
 			b.WriteString("<param>")
-			b.Write(p)
+			if err := enc.encode(b, arg); err != nil {
+				return withParam(err, i)
+			}
 			b.WriteString("</param>")
+			if !direct {
+				if _, err := w.Write(b.Bytes()); err != nil {
+					return err
+				}
+			}
 		}
 
-		b.WriteString("</params>")
+		if _, err := io.WriteString(w, "</params>"); err != nil {
+			return err
+		}
 	}
 
-	b.WriteString("</methodCall>")
+	_, err := io.WriteString(w, "</methodCall>")
+	return err
+}
+
+// escapeChunkSize is the number of bytes of a string that writeStringParam
+// escapes at once.
+const escapeChunkSize = 4 << 10
 
-	return b.Bytes(), nil
+// writeStringParam writes a <param> holding s in a <tag> element to w. s is
+// escaped in chunks split at rune boundaries, so it is not copied in full.
+func writeStringParam(w io.Writer, tag string, s string) error {
+	if _, err := io.WriteString(w, "<param><value><"+tag+">"); err != nil {
+		return err
+	}
+
+	var chunk [escapeChunkSize]byte
+	for len(s) > 0 {
+		n := min(len(s), len(chunk))
+		if n < len(s) {
+			// Do not split a rune, EscapeText would replace both halves.
+			for n > 0 && !utf8.RuneStart(s[n]) {
+				n--
+			}
+			if n == 0 {
+				n = len(chunk)
+			}
+		}
+		copy(chunk[:], s[:n])
+		if err := xml.EscapeText(w, chunk[:n]); err != nil {
+			return err
+		}
+		s = s[n:]
+	}
+
+	_, err := io.WriteString(w, "</"+tag+"></value></param>")
+	return err
 }
@@ -0,0 +1,223 @@
+package xmlrpc
+
+import (
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRequestParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		args      any
+		wantCount int
+		wantParam string
+	}{
+		{"no_args", nil, 0, ""},
+		{"one_nil_arg", []any{nil}, 1, "<param><value/></param>"},
+		{"one_arg", "hello", 1, "<param><value><string>hello</string></value></param>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := NewRequest("http://example.com", "test.method", tt.args)
+			if err != nil {
+				t.Fatalf("NewRequest error: %v", err)
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatalf("io.ReadAll error: %v", err)
+			}
+
+			if got := strings.Count(string(body), "<param>"); got != tt.wantCount {
+				t.Fatalf("expected %d params, got %d in %s", tt.wantCount, got, body)
+			}
+			if tt.wantParam != "" && !strings.Contains(string(body), tt.wantParam) {
+				t.Fatalf("expected %s in %s", tt.wantParam, body)
+			}
+		})
+	}
+}
+
+func TestNewStreamingRequest(t *testing.T) {
+	t.Parallel()
+
+	args := []any{"hello", 42, []any{true, 1.5}}
+
+	want, err := EncodeMethodCall("test.method", args...)
+	if err != nil {
+		t.Fatalf("EncodeMethodCall error: %v", err)
+	}
+
+	req, err := newStreamingRequest(
+		t.Context(),
+		"http://example.com",
+		"test.method",
+		args,
+		encodeOptions{},
+		compressOptions{},
+	)
+	if err != nil {
+		t.Fatalf("newStreamingRequest error: %v", err)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("io.ReadAll error: %v", err)
+	}
+	if string(body) != string(want) {
+		t.Fatalf("body mismatch:\nexpected: %s\n     got: %s", want, body)
+	}
+
+	// GetBody must produce the same body again, e.g. for redirects.
+	rc, err := req.GetBody()
+	if err != nil {
+		t.Fatalf("GetBody error: %v", err)
+	}
+	defer rc.Close()
+
+	body, err = io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("io.ReadAll error: %v", err)
+	}
+	if string(body) != string(want) {
+		t.Fatalf("GetBody mismatch:\nexpected: %s\n     got: %s", want, body)
+	}
+}
+
+func TestWriteMethodCallBounded(t *testing.T) {
+	// Not parallel, the heap statistics are process wide.
+
+	const argSize = 4 << 20
+	str := strings.Repeat("a<b&cé", argSize/8)
+	b64 := Base64(strings.Repeat("QUJD", argSize/4))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := (&encoder{}).writeMethodCall(io.Discard, "upload", str, b64); err != nil {
+		t.Fatalf("writeMethodCall error: %v", err)
+	}
+	runtime.ReadMemStats(&after)
+
+	// Arguments are streamed, never copied or encoded in full.
+	if alloc, limit := after.TotalAlloc-before.TotalAlloc, uint64(256<<10); alloc > limit {
+		t.Fatalf("writeMethodCall allocated %d bytes for %d bytes of arguments, want at most %d", alloc, 2*argSize, limit)
+	}
+}
+
+func TestDecodeMethodCall(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		method string
+		args   []any
+		want   []any
+	}{
+		{"no_params", "system.listMethods", nil, nil},
+		{"empty_params", "ping", []any{}, []any{}},
+		{"escaped_name", "a<b>&c", []any{"x"}, []any{"x"}},
+		{
+			"values",
+			"user.update",
+			[]any{42, "kolo", true, 1.5, time.Date(2013, 12, 9, 21, 0, 12, 0, time.UTC), []int{1, 2}, map[string]any{"k": "v"}},
+			[]any{int64(42), "kolo", true, 1.5, time.Date(2013, 12, 9, 21, 0, 12, 0, time.UTC), []any{int64(1), int64(2)}, map[string]any{"k": "v"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := EncodeMethodCall(tt.method, tt.args...)
+			if err != nil {
+				t.Fatalf("EncodeMethodCall error: %v", err)
+			}
+
+			method, params, err := DecodeMethodCall(data)
+			if err != nil {
+				t.Fatalf("DecodeMethodCall error: %v", err)
+			}
+			if method != tt.method {
+				t.Errorf("expected method %q, got %q", tt.method, method)
+			}
+			if !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("expected params %#v, got %#v", tt.want, params)
+			}
+		})
+	}
+}
+
+func TestDecodeMethodCallInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, data := range []string{
+		"",
+		"<methodResponse><params></params></methodResponse>",
+		"<methodCall><params></params></methodCall>",
+		"<methodCall><methodName>m</methodName><params><value><int>1</int></value></params></methodCall>",
+		"<methodCall><methodName>m</methodName>",
+	} {
+		if _, _, err := DecodeMethodCall([]byte(data)); err == nil {
+			t.Errorf("expected error for %q, got nil", data)
+		}
+	}
+}
+
+func TestEncodeMethodCallErrorPath(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Name    string
+		Handler func()
+	}
+	type Settings struct {
+		Config Config `xmlrpc:"Config"`
+	}
+
+	tests := []struct {
+		name string
+		args []any
+		want string
+	}{
+		{
+			"param",
+			[]any{"a", make(chan int)},
+			"xmlrpc: param 1: unsupported type chan",
+		},
+		{
+			"struct_field",
+			[]any{1, "b", Settings{}},
+			`xmlrpc: param 2: field "Config.Handler": unsupported type func`,
+		},
+		{
+			"array_element",
+			[]any{[]any{1, map[string]any{"items": []any{"x", func() {}}}}},
+			`xmlrpc: param 0: field "[1].items[1]": unsupported type func`,
+		},
+		{
+			"members",
+			[]any{OrderedMap{{"outer", OrderedMap{{"inner", make(chan int)}}}}},
+			`xmlrpc: param 0: field "outer.inner": unsupported type chan`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := EncodeMethodCall("test.method", tt.args...)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("EncodeMethodCall() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,59 @@ package xmlrpc
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 )
 
 // FaultError represents an XML-RPC fault response from the server.
+//
+// The fault code is decoded from an <int>, <i4> or <i8> value, or from a
+// <string> containing a decimal integer. Use [FaultError.Code64] for codes
+// that may not fit into an int.
 type FaultError struct {
 	Code   int    `xmlrpc:"faultCode"`
 	String string `xmlrpc:"faultString"`
+
+	// code64 holds a decoded fault code that does not fit into an int, and
+	// is zero otherwise, so that ordinary faults equal their literals.
+	code64 int64 `xmlrpc:"-"`
+	// raw points to all members of the fault struct. It is a pointer so that
+	// FaultError stays comparable.
+	raw *map[string]any `xmlrpc:"-"`
+}
+
+// RawMembers returns all members of the fault struct, including any beyond
+// faultCode and faultString. It returns nil unless [WithRichFaults] is used.
+func (e FaultError) RawMembers() map[string]any {
+	if e.raw == nil {
+		return nil
+	}
+	return *e.raw
+}
+
+// NewFault returns a [FaultError] with the given fault code and message.
+func NewFault(code int, s string) FaultError {
+	return FaultError{Code: code, String: s}
+}
+
+// Code64 returns the fault code as an int64. It differs from Code only for
+// decoded codes that do not fit into an int on 32-bit platforms.
+func (e FaultError) Code64() int64 {
+	if e.code64 != 0 && int(e.code64) == e.Code {
+		return e.code64
+	}
+	return int64(e.Code)
+}
+
+// setCode sets the fault code, truncating Code if it does not fit into an int.
+func (e *FaultError) setCode(code int64) {
+	e.Code = int(code)
+	e.code64 = 0
+	if int64(e.Code) != code {
+		e.code64 = code
+	}
 }
 
 // Error returns the string representation of the fault.
@@ -16,6 +62,168 @@ func (e FaultError) Error() string {
 	return fmt.Sprintf("Fault(%d): %s", e.Code, e.String)
 }
 
+// Is reports whether target is a [FaultError] or *FaultError with the same
+// fault code, so that errors.Is(err, FaultError{Code: 410}) matches any fault
+// with code 410 regardless of its message.
+func (e FaultError) Is(target error) bool {
+	switch t := target.(type) {
+	case FaultError:
+		return e.Code64() == t.Code64()
+	case *FaultError:
+		return t != nil && e.Code64() == t.Code64()
+	}
+	return false
+}
+
+// EncodeMethodResponse encodes an XML-RPC method response holding values as
+// its parameters into XML bytes. It mirrors [EncodeMethodCall] for servers
+// and proxies; XML-RPC responses normally hold a single value.
+func EncodeMethodResponse(values ...any) ([]byte, error) {
+	var b bytes.Buffer
+	if err := (&encoder{}).writeMethodResponse(&b, values...); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// EncodeFault encodes an XML-RPC fault response with the given fault code
+// and message into XML bytes.
+func EncodeFault(code int, message string) []byte {
+	var b bytes.Buffer
+	(&encoder{}).writeFault(&b, FaultError{Code: code, String: message})
+	return b.Bytes()
+}
+
+// writeMethodResponse writes a <methodResponse> holding values as its
+// parameters to b.
+func (enc *encoder) writeMethodResponse(b *bytes.Buffer, values ...any) error {
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><methodResponse><params>`)
+	for _, v := range values {
+		b.WriteString("<param>")
+		if err := enc.encode(b, v); err != nil {
+			return fmt.Errorf("xmlrpc: failed to encode result: %w", err)
+		}
+		b.WriteString("</param>")
+	}
+	b.WriteString("</params></methodResponse>")
+	return nil
+}
+
+// writeFault writes a <methodResponse> holding fault to b.
+func (enc *encoder) writeFault(b *bytes.Buffer, fault FaultError) {
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><methodResponse><fault>`)
+	// Encoding an int and a string never fails.
+	_ = enc.encode(b, OrderedMap{{"faultCode", fault.Code}, {"faultString", fault.String}})
+	b.WriteString("</fault></methodResponse>")
+}
+
+// HTTPError is returned when the server responds with a non-2xx HTTP status.
+type HTTPError struct {
+	// StatusCode is the HTTP status code, e.g. 503.
+	StatusCode int
+	// Status is the HTTP status line, e.g. "503 Service Unavailable".
+	Status string
+	// Body is the response body after removing any content encoding.
+	Body []byte
+}
+
+// Error returns the error message including the status code.
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("xmlrpc: unexpected status code %d", e.StatusCode)
+}
+
+// FaultConverter converts an error returned by a method into the
+// [FaultError] sent to the caller.
+type FaultConverter func(error) FaultError
+
+// DefaultFaultConverter is the default [FaultConverter]. If err is or wraps a
+// [FaultError], that fault is returned unchanged. Any other error becomes an
+// application error fault with code -32500 and the error message as fault string.
+func DefaultFaultConverter(err error) FaultError {
+	var fault FaultError
+	if errors.As(err, &fault) {
+		return fault
+	}
+	return FaultError{Code: -32500, String: err.Error()}
+}
+
+var (
+	faultStatusMu sync.RWMutex
+
+	// faultToStatus maps fault codes to HTTP status codes. The defaults follow
+	// the interoperability fault codes at
+	// http://xmlrpc-epi.sourceforge.net/specs/rfc.fault_codes.php.
+	faultToStatus = map[int]int{
+		-32700: http.StatusBadRequest,           // parse error: not well formed
+		-32701: http.StatusUnsupportedMediaType, // parse error: unsupported encoding
+		-32702: http.StatusBadRequest,           // parse error: invalid character for encoding
+		-32600: http.StatusBadRequest,           // server error: invalid xml-rpc
+		-32601: http.StatusNotFound,             // server error: requested method not found
+		-32602: http.StatusBadRequest,           // server error: invalid method parameters
+		-32603: http.StatusInternalServerError,  // server error: internal xml-rpc error
+		-32500: http.StatusInternalServerError,  // application error
+		-32400: http.StatusInternalServerError,  // system error
+		-32300: http.StatusBadGateway,           // transport error
+	}
+
+	// statusToFault maps HTTP status codes to fault codes.
+	statusToFault = map[int]int{
+		http.StatusBadRequest:           -32600,
+		http.StatusNotFound:             -32601,
+		http.StatusUnsupportedMediaType: -32701,
+		http.StatusInternalServerError:  -32603,
+		http.StatusBadGateway:           -32300,
+	}
+)
+
+// HTTPStatus returns the HTTP status code corresponding to the fault code.
+//
+// The default mapping covers the interoperability fault codes:
+// parse errors (-32700, -32702), invalid requests (-32600) and invalid
+// parameters (-32602) map to 400, unsupported encoding (-32701) to 415,
+// method not found (-32601) to 404, transport errors (-32300) to 502, and
+// internal, application and system errors (-32603, -32500, -32400) to 500.
+// Any other fault code maps to 500. Use [RegisterFaultHTTPStatus] to add or
+// override mappings.
+func (e FaultError) HTTPStatus() int {
+	faultStatusMu.RLock()
+	defer faultStatusMu.RUnlock()
+
+	if status, ok := faultToStatus[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// FaultFromHTTPStatus returns a [FaultError] for the given HTTP status code
+// with msg as fault string.
+//
+// By default 400 maps to -32600, 404 to -32601, 415 to -32701, 500 to -32603
+// and 502 to -32300. Any other status maps to the transport error -32300.
+// Use [RegisterFaultHTTPStatus] to add or override mappings.
+func FaultFromHTTPStatus(code int, msg string) FaultError {
+	faultStatusMu.RLock()
+	defer faultStatusMu.RUnlock()
+
+	faultCode, ok := statusToFault[code]
+	if !ok {
+		faultCode = -32300
+	}
+	return FaultError{Code: faultCode, String: msg}
+}
+
+// RegisterFaultHTTPStatus registers a mapping between a fault code and an HTTP
+// status code, used by [FaultError.HTTPStatus] and [FaultFromHTTPStatus].
+// It overrides existing mappings in both directions and is safe for
+// concurrent use.
+func RegisterFaultHTTPStatus(faultCode, httpStatus int) {
+	faultStatusMu.Lock()
+	defer faultStatusMu.Unlock()
+
+	faultToStatus[faultCode] = httpStatus
+	statusToFault[httpStatus] = faultCode
+}
+
 // Response represents a raw XML-RPC response body.
 //
 // Deprecated: Response is no longer used internally.
@@ -28,11 +236,12 @@ type Response []byte
 // Deprecated: Use [Client.Call] or [Client.CallContext] instead,
 // which return [FaultError] directly.
 func (r Response) Err() error {
-	if !bytes.Contains(r, []byte("<fault>")) {
+	dec := newDecoder(bytes.NewReader(r), decodeOptions{})
+	if isFault, err := dec.readResponseStart(); err != nil || !isFault {
 		return nil
 	}
 	var fault FaultError
-	if err := unmarshal(r, &fault); err != nil {
+	if err := dec.decodeFaultValue(&fault); err != nil {
 		return fmt.Errorf("xmlrpc: failed to parse fault response: %w", err)
 	}
 	return fault
@@ -40,7 +249,7 @@ func (r Response) Err() error {
 
 // Unmarshal decodes the XML-RPC response into v.
 //
-// Deprecated: Use [Client.Call] or [Client.CallContext] instead.
+// Deprecated: Use [Unmarshal], [Client.Call] or [Client.CallContext] instead.
 func (r Response) Unmarshal(v any) error {
 	return unmarshal(r, v)
 }
@@ -1,6 +1,12 @@
 package xmlrpc
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -35,6 +41,18 @@ func TestResponseErr(t *testing.T) {
 			wantErr:   true,
 			faultCode: 410,
 		},
+		{
+			name: "fault_text_in_string",
+			xml: `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+  <params>
+    <param>
+      <value><string>&lt;fault&gt;</string></value>
+    </param>
+  </params>
+</methodResponse>`,
+			wantErr: false,
+		},
 		{
 			name: "success_response",
 			xml: `<?xml version="1.0" encoding="UTF-8"?>
@@ -164,3 +182,254 @@ func TestFaultErrorString(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, got)
 	}
 }
+
+func TestFaultErrorComparable(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<methodResponse><fault><value><struct>` +
+		`<member><name>faultCode</name><value><int>4</int></value></member>` +
+		`<member><name>faultString</name><value><string>too many</string></value></member>` +
+		`<member><name>traceId</name><value><string>abc-123</string></value></member>` +
+		`</struct></value></fault></methodResponse>`
+
+	var result any
+	rich := Unmarshal([]byte(xml), &result, WithRichFaults())
+	if rich.(FaultError).RawMembers() == nil {
+		t.Fatalf("expected raw members, got %+v", rich)
+	}
+
+	// Comparing errors holding faults must not panic.
+	var err error = FaultError{Code: 4, String: "too many"}
+	if err != error(FaultError{Code: 4, String: "too many"}) {
+		t.Error("expected equal faults to compare equal")
+	}
+	if err == rich {
+		t.Error("expected a fault with raw members to differ from one without")
+	}
+
+	// Decoded faults equal their literals.
+	for _, code := range []string{"<int>4</int>", "<i8>4</i8>", "<string>4</string>"} {
+		decoded := Unmarshal([]byte(`<methodResponse><fault><value><struct>`+
+			`<member><name>faultCode</name><value>`+code+`</value></member>`+
+			`<member><name>faultString</name><value><string>too many</string></value></member>`+
+			`</struct></value></fault></methodResponse>`), &result)
+		if decoded != error(FaultError{Code: 4, String: "too many"}) {
+			t.Errorf("decoded fault %s: expected == literal, got %#v", code, decoded)
+		}
+		if !reflect.DeepEqual(decoded, error(FaultError{Code: 4, String: "too many"})) {
+			t.Errorf("decoded fault %s: expected reflect.DeepEqual to literal, got %#v", code, decoded)
+		}
+	}
+}
+
+func TestFaultErrorCode64(t *testing.T) {
+	t.Parallel()
+
+	if got := (FaultError{Code: -32500}).Code64(); got != -32500 {
+		t.Errorf("Code64() of constructed fault = %d, want -32500", got)
+	}
+
+	var fault FaultError
+	fault.setCode(3)
+	fault.Code = 5
+	if got := fault.Code64(); got != 5 {
+		t.Errorf("Code64() after changing Code = %d, want 5", got)
+	}
+}
+
+func TestFaultErrorIs(t *testing.T) {
+	t.Parallel()
+
+	const xml = `<?xml version="1.0"?>
+<methodResponse><fault><value><struct>
+<member><name>faultCode</name><value><int>410</int></value></member>
+<member><name>faultString</name><value><string>You must log in</string></value></member>
+</struct></value></fault></methodResponse>`
+
+	var result any
+	err := Unmarshal([]byte(xml), &result)
+	wrapped := fmt.Errorf("login: %w", err)
+
+	tests := []struct {
+		name   string
+		target error
+		want   bool
+	}{
+		{"same_code", FaultError{Code: 410}, true},
+		{"new_fault", NewFault(410, "other message"), true},
+		{"pointer", &FaultError{Code: 410}, true},
+		{"other_code", FaultError{Code: 411}, false},
+		{"nil_pointer", (*FaultError)(nil), false},
+		{"other_error", errors.New("Fault(410): You must log in"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errors.Is(wrapped, tt.target); got != tt.want {
+				t.Errorf("errors.Is(%v, %v) = %v, want %v", wrapped, tt.target, got, tt.want)
+			}
+		})
+	}
+
+	var fault FaultError
+	if !errors.As(wrapped, &fault) {
+		t.Fatalf("errors.As(%v) failed", wrapped)
+	}
+	if want := NewFault(410, "You must log in"); fault.Code != want.Code || fault.String != want.String {
+		t.Errorf("errors.As() = %+v, want %+v", fault, want)
+	}
+}
+
+func TestFaultHTTPStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		faultCode int
+		status    int
+	}{
+		{-32600, http.StatusBadRequest},
+		{-32601, http.StatusNotFound},
+		{-32701, http.StatusUnsupportedMediaType},
+		{-32603, http.StatusInternalServerError},
+		{-32300, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.faultCode), func(t *testing.T) {
+			t.Parallel()
+
+			if got := (FaultError{Code: tt.faultCode}).HTTPStatus(); got != tt.status {
+				t.Errorf("HTTPStatus: expected %d, got %d", tt.status, got)
+			}
+
+			fault := FaultFromHTTPStatus(tt.status, "message")
+			if fault.Code != tt.faultCode || fault.String != "message" {
+				t.Errorf("FaultFromHTTPStatus: expected code %d, got %+v", tt.faultCode, fault)
+			}
+		})
+	}
+}
+
+func TestFaultHTTPStatusDefaults(t *testing.T) {
+	t.Parallel()
+
+	if got := (FaultError{Code: 4}).HTTPStatus(); got != http.StatusInternalServerError {
+		t.Errorf("expected %d for unknown fault code, got %d", http.StatusInternalServerError, got)
+	}
+	if got := FaultFromHTTPStatus(http.StatusTeapot, "").Code; got != -32300 {
+		t.Errorf("expected -32300 for unknown status, got %d", got)
+	}
+}
+
+func TestRegisterFaultHTTPStatus(t *testing.T) {
+	t.Parallel()
+
+	RegisterFaultHTTPStatus(1001, http.StatusConflict)
+
+	if got := (FaultError{Code: 1001}).HTTPStatus(); got != http.StatusConflict {
+		t.Errorf("expected %d, got %d", http.StatusConflict, got)
+	}
+	if got := FaultFromHTTPStatus(http.StatusConflict, "").Code; got != 1001 {
+		t.Errorf("expected fault code 1001, got %d", got)
+	}
+}
+
+func TestDefaultFaultConverter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want FaultError
+	}{
+		{"plain_error", errors.New("boom"), FaultError{Code: -32500, String: "boom"}},
+		{"fault", FaultError{Code: 4, String: "too many"}, FaultError{Code: 4, String: "too many"}},
+		{
+			"wrapped_fault",
+			fmt.Errorf("wrapped: %w", FaultError{Code: 4, String: "too many"}),
+			FaultError{Code: 4, String: "too many"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := DefaultFaultConverter(tt.err); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomFaultConverter(t *testing.T) {
+	t.Parallel()
+
+	var convert FaultConverter = func(err error) FaultError {
+		if errors.Is(err, os.ErrNotExist) {
+			return FaultError{Code: 404, String: "not found"}
+		}
+		return DefaultFaultConverter(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want FaultError
+	}{
+		{"converted", fmt.Errorf("open: %w", os.ErrNotExist), FaultError{Code: 404, String: "not found"}},
+		{"default", errors.New("boom"), FaultError{Code: -32500, String: "boom"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := convert(tt.err); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeMethodResponse(t *testing.T) {
+	t.Parallel()
+
+	b, err := EncodeMethodResponse(map[string]any{"name": "a&b", "count": 2})
+	if err != nil {
+		t.Fatalf("EncodeMethodResponse error: %v", err)
+	}
+	const expected = `<?xml version="1.0" encoding="UTF-8"?><methodResponse><params><param>` +
+		`<value><struct><member><name>count</name><value><int>2</int></value></member>` +
+		`<member><name>name</name><value><string>a&amp;b</string></value></member></struct></value>` +
+		`</param></params></methodResponse>`
+	if string(b) != expected {
+		t.Fatalf("EncodeMethodResponse error:\nexpected: %s\n     got: %s", expected, b)
+	}
+
+	if _, err := EncodeMethodResponse(make(chan int)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestEncodeFault(t *testing.T) {
+	t.Parallel()
+
+	b := EncodeFault(-32601, `method "a<b>" not found & gone`)
+	const expected = `<?xml version="1.0" encoding="UTF-8"?><methodResponse><fault>` +
+		`<value><struct><member><name>faultCode</name><value><int>-32601</int></value></member>` +
+		`<member><name>faultString</name><value><string>method &#34;a&lt;b&gt;&#34; not found &amp; gone</string></value></member></struct></value>` +
+		`</fault></methodResponse>`
+	if string(b) != expected {
+		t.Fatalf("EncodeFault error:\nexpected: %s\n     got: %s", expected, b)
+	}
+
+	var v any
+	err := Unmarshal(b, &v)
+	var fault FaultError
+	if !errors.As(err, &fault) || fault.Code != -32601 || fault.String != `method "a<b>" not found & gone` {
+		t.Fatalf("expected decoded fault, got %v", err)
+	}
+}
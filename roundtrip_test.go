@@ -1,6 +1,9 @@
 package xmlrpc
 
 import (
+	"bytes"
+	"math"
+	"math/big"
 	"reflect"
 	"testing"
 	"time"
@@ -28,6 +31,69 @@ func TestRoundTripInt(t *testing.T) {
 	}
 }
 
+func TestRoundTripInt64(t *testing.T) {
+	t.Parallel()
+
+	values := []int64{0, 2147483648, -2147483649, math.MaxInt64, math.MinInt64}
+
+	for _, original := range values {
+		encoded, err := marshal(original)
+		if err != nil {
+			t.Fatalf("marshal(%d) error: %v", original, err)
+		}
+
+		var decoded int64
+		if err := unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+
+		if original != decoded {
+			t.Errorf("round-trip failed: original=%d, decoded=%d", original, decoded)
+		}
+	}
+}
+
+func TestRoundTripBigInt(t *testing.T) {
+	t.Parallel()
+
+	tooLarge, _ := new(big.Int).SetString("9223372036854775808", 10)
+	tooSmall, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+
+	tests := []struct {
+		name  string
+		value *big.Int
+		xml   string
+	}{
+		{"small", big.NewInt(-42), "<value><int>-42</int></value>"},
+		{"max_int64", big.NewInt(math.MaxInt64), "<value><i8>9223372036854775807</i8></value>"},
+		{"min_int64", big.NewInt(math.MinInt64), "<value><i8>-9223372036854775808</i8></value>"},
+		{"above_int64", tooLarge, "<value><string>9223372036854775808</string></value>"},
+		{"negative_below_int64", tooSmall, "<value><string>-123456789012345678901234567890</string></value>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			encoded, err := marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal(%v) error: %v", tt.value, err)
+			}
+			if string(encoded) != tt.xml {
+				t.Fatalf("marshal error:\nexpected: %s\n     got: %s", tt.xml, encoded)
+			}
+
+			var decoded *big.Int
+			if err := unmarshal(encoded, &decoded); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if decoded.Cmp(tt.value) != 0 {
+				t.Errorf("round-trip failed: original=%v, decoded=%v", tt.value, decoded)
+			}
+		})
+	}
+}
+
 func TestRoundTripString(t *testing.T) {
 	t.Parallel()
 
@@ -125,6 +191,38 @@ func TestRoundTripTime(t *testing.T) {
 	}
 }
 
+func TestRoundTripTimeZoneOffset(t *testing.T) {
+	t.Parallel()
+
+	enc := &encoder{opts: encodeOptions{timeZoneOffset: true}}
+
+	values := []time.Time{
+		time.Date(2013, 12, 9, 21, 0, 12, 0, time.FixedZone("", 3600)),
+		time.Date(2013, 12, 9, 21, 0, 12, 0, time.FixedZone("", -5*3600-1800)),
+		time.Date(2013, 12, 9, 21, 0, 12, 0, time.UTC),
+	}
+
+	for _, original := range values {
+		encoded, err := enc.marshal(original)
+		if err != nil {
+			t.Fatalf("marshal(%v) error: %v", original, err)
+		}
+
+		var decoded time.Time
+		if err := unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+
+		if !original.Equal(decoded) {
+			t.Errorf("round-trip failed: original=%v, decoded=%v", original, decoded)
+		}
+		_, wantOffset := original.Zone()
+		if _, gotOffset := decoded.Zone(); gotOffset != wantOffset {
+			t.Errorf("zone offset lost: original=%d, decoded=%d", wantOffset, gotOffset)
+		}
+	}
+}
+
 func TestRoundTripSlice(t *testing.T) {
 	t.Parallel()
 
@@ -206,6 +304,62 @@ func TestRoundTripNestedStruct(t *testing.T) {
 	}
 }
 
+func TestRoundTripEmbeddedStruct(t *testing.T) {
+	t.Parallel()
+
+	type Base struct {
+		ID      int    `xmlrpc:"id"`
+		Secret  string `xmlrpc:"-"`
+		Comment string `xmlrpc:"comment,omitempty"`
+	}
+	type Audit struct {
+		Created string `xmlrpc:"created"`
+	}
+	type meta struct {
+		Tag string `xmlrpc:"tag"`
+	}
+	type User struct {
+		Base
+		*Audit
+		meta
+		Name string `xmlrpc:"name"`
+		note string
+	}
+
+	original := User{
+		Base:  Base{ID: 7, Secret: "hidden"},
+		Audit: &Audit{Created: "today"},
+		meta:  meta{Tag: "admin"},
+		Name:  "John Doe",
+		note:  "unexported",
+	}
+
+	encoded, err := marshal(&original)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	expected := "<value><struct>" +
+		"<member><name>id</name><value><int>7</int></value></member>" +
+		"<member><name>created</name><value><string>today</string></value></member>" +
+		"<member><name>tag</name><value><string>admin</string></value></member>" +
+		"<member><name>name</name><value><string>John Doe</string></value></member>" +
+		"</struct></value>"
+	if string(encoded) != expected {
+		t.Fatalf("marshal error:\nexpected: %s\n     got: %s", expected, encoded)
+	}
+
+	var decoded User
+	if err := unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	original.Secret = ""
+	original.note = ""
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("round-trip failed:\noriginal=%+v\ndecoded=%+v", original, decoded)
+	}
+}
+
 func TestRoundTripMap(t *testing.T) {
 	t.Parallel()
 
@@ -232,3 +386,77 @@ func TestRoundTripMap(t *testing.T) {
 		t.Errorf("bool mismatch: got %v", decoded["bool"])
 	}
 }
+
+func TestRoundTripOrderedMap(t *testing.T) {
+	t.Parallel()
+
+	original := OrderedMap{
+		{"zeta", int64(1)},
+		{"alpha", "a"},
+		{"mid", []any{int64(2), "b"}},
+	}
+
+	encoded, err := marshal(original)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	var decoded OrderedMap
+	if err := unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("round-trip failed:\noriginal=%#v\ndecoded=%#v", original, decoded)
+	}
+}
+
+func TestRoundTripJSONTags(t *testing.T) {
+	t.Parallel()
+
+	type Account struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name,omitempty"`
+		Email    string `json:"email,omitempty"`
+		Password string `json:"-"`
+		Login    string `json:"login" xmlrpc:"user_login"`
+		Active   bool
+	}
+
+	original := Account{ID: 7, Name: "John Doe", Password: "secret", Login: "jdoe", Active: true}
+
+	enc := &encoder{opts: encodeOptions{jsonTags: true}}
+	encoded, err := enc.marshal(&original)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	expected := "<value><struct>" +
+		"<member><name>id</name><value><int>7</int></value></member>" +
+		"<member><name>name</name><value><string>John Doe</string></value></member>" +
+		"<member><name>user_login</name><value><string>jdoe</string></value></member>" +
+		"<member><name>Active</name><value><boolean>1</boolean></value></member>" +
+		"</struct></value>"
+	if string(encoded) != expected {
+		t.Fatalf("marshal error:\nexpected: %s\n     got: %s", expected, encoded)
+	}
+
+	var decoded Account
+	dec := newDecoder(bytes.NewReader(encoded), decodeOptions{jsonTags: true})
+	if err := dec.unmarshal(&decoded); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	original.Password = ""
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("round-trip failed:\noriginal=%+v\ndecoded=%+v", original, decoded)
+	}
+
+	// Without the option, json tags are ignored.
+	encoded, err = marshal(&original)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if !bytes.Contains(encoded, []byte("<name>ID</name>")) {
+		t.Errorf("expected Go field names without WithJSONTags, got %s", encoded)
+	}
+}
@@ -0,0 +1,100 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Method is an XML-RPC method served by a [Handler]. It receives the
+// parameters of the call decoded as described for [Unmarshal] and returns
+// the result, or an error that is sent to the caller as a fault.
+type Method func(args ...any) (any, error)
+
+// Handler is an [http.Handler] serving XML-RPC calls to the methods
+// registered with [Handler.Register], e.g. as a test double for a server:
+//
+//	h := xmlrpc.NewHandler()
+//	h.Register("math.add", func(args ...any) (any, error) {
+//		return args[0].(int64) + args[1].(int64), nil
+//	})
+//	http.Handle("/RPC2", h)
+//
+// Faults are sent with the HTTP status 200, as the XML-RPC specification
+// requires.
+type Handler struct {
+	// FaultConverter converts errors returned by methods into the faults
+	// sent to the caller. If nil, [DefaultFaultConverter] is used.
+	FaultConverter FaultConverter
+
+	mu      sync.RWMutex
+	methods map[string]Method
+}
+
+// NewHandler returns a [Handler] without any methods.
+func NewHandler() *Handler {
+	return &Handler{methods: make(map[string]Method)}
+}
+
+// Register registers fn as the method called name, replacing any method
+// registered under the same name. It is safe for concurrent use.
+func (h *Handler) Register(name string, fn Method) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.methods[name] = fn
+}
+
+// ServeHTTP decodes the <methodCall> in the request body, calls the method
+// it names and responds with its result or a fault. Unknown methods are
+// answered with the fault -32601 and malformed calls with -32700.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	enc := &encoder{}
+	var b bytes.Buffer
+
+	name, params, err := newDecoder(r.Body, decodeOptions{}).decodeMethodCall()
+	if err != nil {
+		enc.writeFault(&b, FaultError{Code: -32700, String: "parse error: " + err.Error()})
+		writeResponse(w, b.Bytes())
+		return
+	}
+
+	h.mu.RLock()
+	fn, ok := h.methods[name]
+	h.mu.RUnlock()
+	if !ok {
+		enc.writeFault(&b, FaultError{Code: -32601, String: "requested method not found: " + name})
+		writeResponse(w, b.Bytes())
+		return
+	}
+
+	result, err := fn(params...)
+	if err != nil {
+		convert := h.FaultConverter
+		if convert == nil {
+			convert = DefaultFaultConverter
+		}
+		enc.writeFault(&b, convert(err))
+		writeResponse(w, b.Bytes())
+		return
+	}
+
+	if err := enc.writeMethodResponse(&b, result); err != nil {
+		b.Reset()
+		enc.writeFault(&b, FaultError{Code: -32603, String: "internal error: " + err.Error()})
+	}
+	writeResponse(w, b.Bytes())
+}
+
+// writeResponse writes body as the XML response to w.
+func writeResponse(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,170 @@
+package xmlrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newHandlerClient(t *testing.T, h *Handler) *Client {
+	t.Helper()
+
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	client, err := NewClientWithOptions(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	h := NewHandler()
+	h.Register("math.add", func(args ...any) (any, error) {
+		var sum int64
+		for _, arg := range args {
+			n, ok := arg.(int64)
+			if !ok {
+				return nil, FaultError{Code: 4, String: "arguments must be integers"}
+			}
+			sum += n
+		}
+		return sum, nil
+	})
+	h.Register("user.get", func(args ...any) (any, error) {
+		return map[string]any{"name": "kolo", "tags": []string{"a", "b"}}, nil
+	})
+	h.Register("fail", func(args ...any) (any, error) {
+		return nil, errors.New("something <broke>")
+	})
+
+	client := newHandlerClient(t, h)
+	ctx := context.Background()
+
+	var sum int
+	if err := client.CallContext(ctx, "math.add", []any{1, 2, 3}, &sum); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if sum != 6 {
+		t.Errorf("expected 6, got %d", sum)
+	}
+
+	var user struct {
+		Name string   `xmlrpc:"name"`
+		Tags []string `xmlrpc:"tags"`
+	}
+	if err := client.CallContext(ctx, "user.get", 7, &user); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if user.Name != "kolo" || strings.Join(user.Tags, ",") != "a,b" {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		args   any
+		code   int
+		str    string
+	}{
+		{"fault", "math.add", []any{1, "two"}, 4, "arguments must be integers"},
+		{"error", "fail", nil, -32500, "something <broke>"},
+		{"not_found", "no.such", nil, -32601, "requested method not found: no.such"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := client.CallContext(ctx, tt.method, tt.args, nil)
+			var fault FaultError
+			if !errors.As(err, &fault) {
+				t.Fatalf("expected FaultError, got %T: %v", err, err)
+			}
+			if fault.Code != tt.code || fault.String != tt.str {
+				t.Errorf("expected fault %d %q, got %d %q", tt.code, tt.str, fault.Code, fault.String)
+			}
+		})
+	}
+}
+
+func TestHandlerFaultConverter(t *testing.T) {
+	t.Parallel()
+
+	h := NewHandler()
+	h.FaultConverter = func(err error) FaultError {
+		if errors.Is(err, os.ErrNotExist) {
+			return FaultError{Code: 404, String: "not found"}
+		}
+		return DefaultFaultConverter(err)
+	}
+	h.Register("file.read", func(args ...any) (any, error) {
+		return nil, fmt.Errorf("open: %w", os.ErrNotExist)
+	})
+	h.Register("fail", func(args ...any) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	client := newHandlerClient(t, h)
+
+	tests := []struct {
+		method string
+		want   FaultError
+	}{
+		{"file.read", FaultError{Code: 404, String: "not found"}},
+		{"fail", FaultError{Code: -32500, String: "boom"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			t.Parallel()
+
+			err := client.CallContext(t.Context(), tt.method, nil, nil)
+			var fault FaultError
+			if !errors.As(err, &fault) {
+				t.Fatalf("expected FaultError, got %T: %v", err, err)
+			}
+			if fault != tt.want {
+				t.Errorf("expected fault %+v, got %+v", tt.want, fault)
+			}
+		})
+	}
+}
+
+func TestHandlerInvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(NewHandler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL, "text/xml", strings.NewReader("<methodCall><params>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result any
+	err = newDecoder(resp.Body, decodeOptions{}).unmarshalResponse(&result)
+	var fault FaultError
+	if !errors.As(err, &fault) || fault.Code != -32700 {
+		t.Errorf("expected parse error fault -32700, got %v", err)
+	}
+}
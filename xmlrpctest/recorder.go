@@ -0,0 +1,248 @@
+// Package xmlrpctest provides utilities for testing XML-RPC clients.
+//
+// A [Recorder] is an [http.RoundTripper] that records XML-RPC calls made
+// against a real server to fixture files, and replays them later without
+// the server:
+//
+//	rec := xmlrpctest.NewRecorder(xmlrpctest.ModeReplay, "testdata/fixtures", nil)
+//	client, err := xmlrpc.NewClientWithOptions(url, xmlrpc.WithTransport(rec))
+package xmlrpctest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ninech/xmlrpc"
+)
+
+// Mode selects whether a [Recorder] records or replays calls.
+type Mode int
+
+const (
+	// ModeRecord forwards calls to the server and saves each response.
+	ModeRecord Mode = iota
+	// ModeReplay serves calls from saved responses without a server.
+	ModeReplay
+)
+
+// Recorder is an [http.RoundTripper] that records XML-RPC calls to fixture
+// files in a directory and replays them. Use it with [xmlrpc.WithTransport].
+//
+// Calls are matched by method name and arguments. The arguments are decoded
+// and encoded again before matching, so differences in formatting or in the
+// order of struct members do not prevent a match. Recording a call again
+// overwrites its fixture.
+type Recorder struct {
+	mode      Mode
+	dir       string
+	transport http.RoundTripper
+
+	// mu serializes fixture file access.
+	mu sync.Mutex
+}
+
+// NewRecorder creates a Recorder storing fixtures in dir. In [ModeRecord],
+// calls are forwarded using transport, or [http.DefaultTransport] if it is
+// nil, and dir is created if needed. In [ModeReplay] transport is not used.
+func NewRecorder(mode Mode, dir string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{mode: mode, dir: dir, transport: transport}
+}
+
+// fixture is a recorded call as stored on disk.
+type fixture struct {
+	Method     string      `json:"method"`
+	Request    string      `json:"request"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	// Body holds the response body if it is valid UTF-8, BinaryBody otherwise.
+	Body       string `json:"body,omitempty"`
+	BinaryBody []byte `json:"binaryBody,omitempty"`
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	method, normalized, err := normalizeCall(body, req.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(normalized)
+	path := filepath.Join(r.dir, fixtureName(method)+"_"+hex.EncodeToString(sum[:8])+".json")
+
+	if r.mode == ModeReplay {
+		return r.replay(req, path)
+	}
+	return r.record(req, body, path, method, normalized)
+}
+
+func (r *Recorder) record(
+	req *http.Request,
+	body []byte,
+	path string,
+	method string,
+	normalized []byte,
+) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	out.ContentLength = int64(len(body))
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f := fixture{
+		Method:     method,
+		Request:    string(normalized),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+	if utf8.Valid(respBody) {
+		f.Body = string(respBody)
+	} else {
+		f.BinaryBody = respBody
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+
+	return newResponse(req, &f), nil
+}
+
+func (r *Recorder) replay(req *http.Request, path string) (*http.Response, error) {
+	r.mu.Lock()
+	data, err := os.ReadFile(path)
+	r.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("xmlrpctest: no recorded response: %w", err)
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("xmlrpctest: invalid fixture %s: %w", path, err)
+	}
+	return newResponse(req, &f), nil
+}
+
+// newResponse builds the response for req from a recorded fixture.
+func newResponse(req *http.Request, f *fixture) *http.Response {
+	body := []byte(f.Body)
+	if f.BinaryBody != nil {
+		body = f.BinaryBody
+	}
+
+	header := f.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// methodCall is the structure of an XML-RPC request body.
+type methodCall struct {
+	Name   string `xml:"methodName"`
+	Params []struct {
+		Value []byte `xml:",innerxml"`
+	} `xml:"params>param"`
+}
+
+// normalizeCall returns the method name of the call in body and the call
+// encoded again in canonical form. Parameters that cannot be decoded are
+// kept verbatim.
+func normalizeCall(body []byte, encoding string) (string, []byte, error) {
+	if strings.EqualFold(encoding, "gzip") {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return "", nil, fmt.Errorf("xmlrpctest: failed to read gzip request: %w", err)
+		}
+		if body, err = io.ReadAll(zr); err != nil {
+			return "", nil, fmt.Errorf("xmlrpctest: failed to read gzip request: %w", err)
+		}
+	}
+
+	var call methodCall
+	if err := xml.Unmarshal(body, &call); err != nil {
+		return "", nil, fmt.Errorf("xmlrpctest: invalid method call: %w", err)
+	}
+
+	var b bytes.Buffer
+	b.WriteString(call.Name)
+	for _, p := range call.Params {
+		var v any
+		if err := xmlrpc.Unmarshal(p.Value, &v); err == nil {
+			if p.Value, err = xmlrpc.EncodeMethodCall("", v); err != nil {
+				return "", nil, err
+			}
+		}
+		b.WriteByte('\n')
+		b.Write(bytes.TrimSpace(p.Value))
+	}
+	return call.Name, b.Bytes(), nil
+}
+
+// fixtureName returns method with characters that are unsafe in file names
+// replaced.
+func fixtureName(method string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, method)
+}
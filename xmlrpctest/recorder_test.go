@@ -0,0 +1,147 @@
+package xmlrpctest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ninech/xmlrpc"
+)
+
+func TestRecorderRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp := `<methodResponse><params><param><value><struct>` +
+			`<member><name>name</name><value><string>kolo</string></value></member>` +
+			`<member><name>tags</name><value><array><data><value><string>a</string></value></data></array></value></member>` +
+			`</struct></value></param></params></methodResponse>`
+		if strings.Contains(string(body), "user.missing") {
+			resp = `<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value><int>404</int></value></member>` +
+				`<member><name>faultString</name><value><string>not found</string></value></member>` +
+				`</struct></value></fault></methodResponse>`
+		}
+		if _, err := io.WriteString(w, resp); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	args := []any{map[string]any{"id": 1, "active": true}}
+
+	type user struct {
+		Name string   `xmlrpc:"name"`
+		Tags []string `xmlrpc:"tags"`
+	}
+
+	call := func(mode Mode, args any) (user, error) {
+		client, err := xmlrpc.NewClientWithOptions(ts.URL, xmlrpc.WithTransport(NewRecorder(mode, dir, nil)))
+		if err != nil {
+			t.Fatalf("NewClientWithOptions error: %v", err)
+		}
+		defer client.Close()
+
+		var u user
+		err = client.Call("user.get", args, &u)
+		return u, err
+	}
+
+	recorded, err := call(ModeRecord, args)
+	if err != nil {
+		t.Fatalf("record call error: %v", err)
+	}
+
+	client, err := xmlrpc.NewClientWithOptions(ts.URL, xmlrpc.WithTransport(NewRecorder(ModeRecord, dir, nil)))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+	if err := client.Call("user.missing", nil, nil); err == nil {
+		t.Fatal("expected fault when recording, got nil")
+	}
+
+	ts.Close()
+	recordedCalls := calls.Load()
+
+	// The same arguments with struct members in a different order match.
+	replayed, err := call(ModeReplay, []any{xmlrpc.OrderedMap{{Name: "active", Value: true}, {Name: "id", Value: 1}}})
+	if err != nil {
+		t.Fatalf("replay call error: %v", err)
+	}
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Fatalf("replayed result differs:\nrecorded=%+v\nreplayed=%+v", recorded, replayed)
+	}
+	if want := (user{Name: "kolo", Tags: []string{"a"}}); !reflect.DeepEqual(replayed, want) {
+		t.Fatalf("expected %+v, got %+v", want, replayed)
+	}
+
+	client, err = xmlrpc.NewClientWithOptions(ts.URL, xmlrpc.WithTransport(NewRecorder(ModeReplay, dir, nil)))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions error: %v", err)
+	}
+	defer client.Close()
+	var fault xmlrpc.FaultError
+	if err := client.Call("user.missing", nil, nil); !errors.As(err, &fault) || fault.Code != 404 {
+		t.Fatalf("expected replayed fault 404, got %v", err)
+	}
+
+	if _, err := call(ModeReplay, []any{map[string]any{"id": 2}}); err == nil {
+		t.Fatal("expected error for call without fixture, got nil")
+	}
+	if got := calls.Load(); got != recordedCalls {
+		t.Fatalf("replay reached the server: %d calls, want %d", got, recordedCalls)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 fixtures, got %d", len(entries))
+	}
+}
+
+func TestRecorderCompressedRequest(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>`)
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	for _, mode := range []Mode{ModeRecord, ModeReplay} {
+		opts := []xmlrpc.Option{xmlrpc.WithTransport(NewRecorder(mode, dir, nil))}
+		if mode == ModeRecord {
+			opts = append(opts, xmlrpc.WithRequestCompression("gzip"))
+		}
+		client, err := xmlrpc.NewClientWithOptions(ts.URL, opts...)
+		if err != nil {
+			t.Fatalf("NewClientWithOptions error: %v", err)
+		}
+
+		var n int
+		if err := client.Call("answer", []any{"life"}, &n); err != nil {
+			t.Fatalf("mode %d: Call error: %v", mode, err)
+		}
+		if n != 42 {
+			t.Fatalf("mode %d: expected 42, got %d", mode, n)
+		}
+		ts.Close()
+	}
+}